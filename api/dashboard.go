@@ -0,0 +1,21 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// Static files of the web dashboard
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// Serves the dashboard files, index.html on /
+func dashboardHandler() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(files)
+}
@@ -0,0 +1,324 @@
+"use strict";
+
+// Key of the API token in the browser's local storage
+const TOKEN_KEY = "brother-cube-token";
+// Delay between the last keystroke and the preview request
+const PREVIEW_DELAY_MS = 600;
+// Interval between status and history refreshes
+const REFRESH_INTERVAL_MS = 15000;
+
+const $ = (id) => document.getElementById(id);
+
+let presets = [];
+let started = false;
+let previewTimer = null;
+let previewRequest = 0;
+let previewURL = null;
+
+// Raised for 401 answers, the token is asked for again
+class UnauthorizedError extends Error {}
+
+// Calls an API endpoint with the stored token and returns the response
+async function api(path, options = {}) {
+  const token = localStorage.getItem(TOKEN_KEY);
+  if (!token) {
+    throw new UnauthorizedError("missing token");
+  }
+
+  const headers = Object.assign({ Authorization: "Bearer " + token }, options.headers);
+  const response = await fetch(path, Object.assign({}, options, { headers }));
+  if (response.status === 401) {
+    throw new UnauthorizedError("invalid token");
+  }
+  if (!response.ok) {
+    let message = response.statusText;
+    try {
+      message = (await response.json()).error || message;
+    } catch (e) {
+      // Not a JSON error body
+    }
+    throw new Error(message);
+  }
+  return response;
+}
+
+// Asks for the token, the dashboard is loaded again once it is entered
+function login(message) {
+  $("login-error").textContent = message || "";
+  if (!$("login").open) {
+    $("token").value = "";
+    $("login").showModal();
+  }
+}
+
+function handleError(err, element) {
+  if (err instanceof UnauthorizedError) {
+    localStorage.removeItem(TOKEN_KEY);
+    login(err.message === "invalid token" ? "The token was not accepted." : "");
+    return;
+  }
+  if (element) {
+    element.textContent = "❌ " + err.message;
+    element.className = "error";
+  }
+}
+
+// Builds the body of POST /print and POST /preview from the form
+function labelRequest() {
+  const request = { text: $("text").value };
+  if ($("preset").value) {
+    request.preset = $("preset").value;
+  }
+  if ($("printer").value) {
+    request.printer = $("printer").value;
+  }
+  const copies = parseInt($("copies").value, 10);
+  if (copies > 0) {
+    request.copies = copies;
+  }
+  return request;
+}
+
+function postLabel(path, request) {
+  return api(path, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(request),
+  });
+}
+
+function formatTime(value) {
+  return new Date(value).toLocaleString([], { dateStyle: "short", timeStyle: "short" });
+}
+
+function formatDuration(seconds) {
+  const hours = Math.floor(seconds / 3600);
+  const minutes = Math.floor((seconds % 3600) / 60);
+  return hours > 0 ? `${hours}h ${minutes}m` : `${minutes}m`;
+}
+
+// Appends an element with the given text, the text is never parsed as HTML
+function append(parent, tag, text, className) {
+  const element = document.createElement(tag);
+  element.textContent = text;
+  if (className) {
+    element.className = className;
+  }
+  parent.appendChild(element);
+  return element;
+}
+
+function renderPrinters(printers) {
+  const container = $("printers");
+  container.replaceChildren();
+
+  const select = $("printer");
+  const selected = select.value;
+  select.replaceChildren();
+  append(select, "option", "Default").value = "";
+
+  for (const printer of printers) {
+    const card = append(container, "div", "", "card");
+    append(card, "h3", "🖨️ " + printer.name);
+
+    if (!printer.available) {
+      append(card, "p", "❌ Not available", "error");
+    } else {
+      append(select, "option", printer.name).value = printer.name;
+
+      const power = printer.power;
+      if (power && power.managed) {
+        let state = power.on ? "🟢 On" : "⚪ Off";
+        if (power.hold_until) {
+          state += " until " + formatTime(power.hold_until);
+        } else if (power.shutdown_at) {
+          state += ", off at " + formatTime(power.shutdown_at);
+        }
+        append(card, "p", state);
+        append(card, "p", `On for ${formatDuration(power.on_time_seconds)}, ${power.power_cycles} power cycle(s)`);
+      } else {
+        append(card, "p", "🟢 Always on");
+      }
+
+      if (printer.info) {
+        const tape = printer.info.tape_width_mm ? `${printer.info.tape_width_mm}mm ${printer.info.media_type || "tape"}` : "no tape";
+        append(card, "p", `${printer.info.model || "Printer"}, ${tape}`);
+      }
+      if (printer.pending_jobs > 0) {
+        append(card, "p", `⏳ ${printer.pending_jobs} job(s) waiting`);
+      }
+    }
+
+    for (const error of printer.errors || []) {
+      append(card, "p", "⚠️ " + error, "error");
+    }
+  }
+
+  select.value = [...select.options].some((option) => option.value === selected) ? selected : "";
+}
+
+function renderHistory(entries) {
+  const body = $("history");
+  body.replaceChildren();
+  if (entries.length === 0) {
+    const cell = append(append(body, "tr", ""), "td", "Nothing printed yet.", "hint");
+    cell.colSpan = 6;
+    return;
+  }
+
+  for (const entry of entries) {
+    const row = append(body, "tr", "");
+    append(row, "td", formatTime(entry.time));
+    append(row, "td", entry.user);
+    append(row, "td", entry.printer || "");
+    append(row, "td", entry.preset || "");
+    append(row, "td", entry.text);
+    const outcome = append(row, "td", entry.outcome, entry.error ? "error" : "");
+    if (entry.error) {
+      outcome.title = entry.error;
+    }
+  }
+}
+
+function renderPresets() {
+  const select = $("preset");
+  for (const preset of presets) {
+    append(select, "option", preset.description ? `${preset.name} – ${preset.description}` : preset.name).value = preset.name;
+  }
+}
+
+function showPresetInfo() {
+  const preset = presets.find((p) => p.name === $("preset").value);
+  if (!preset) {
+    $("preset-info").textContent = "";
+    return;
+  }
+
+  const info = [`font size ${preset.font_size}`];
+  if (preset.font_family) info.push(`font ${preset.font_family}`);
+  if (preset.barcode) info.push(`barcode ${preset.barcode}`);
+  if (preset.copies > 1) info.push(`${preset.copies} copies`);
+  if (preset.printer) info.push(`printer ${preset.printer}`);
+  if (preset.template) info.push(`template ${preset.template}`);
+  $("preset-info").textContent = info.join(", ");
+}
+
+async function refresh() {
+  try {
+    const [status, history] = await Promise.all([
+      api("/status").then((r) => r.json()),
+      api("/history").then((r) => r.json()).catch((err) => {
+        if (err instanceof UnauthorizedError) throw err;
+        return [];
+      }),
+    ]);
+    renderPrinters(status);
+    renderHistory(history);
+  } catch (err) {
+    handleError(err, null);
+  }
+}
+
+// Requests a preview once typing has paused
+function schedulePreview() {
+  clearTimeout(previewTimer);
+  previewTimer = setTimeout(updatePreview, PREVIEW_DELAY_MS);
+}
+
+async function updatePreview() {
+  const request = labelRequest();
+  const message = $("preview-message");
+  const image = $("preview");
+  const id = ++previewRequest;
+
+  if (request.text.trim() === "") {
+    image.hidden = true;
+    message.textContent = "The preview appears while you type.";
+    message.className = "hint";
+    return;
+  }
+
+  message.textContent = "Rendering preview...";
+  message.className = "hint";
+  try {
+    const blob = await (await postLabel("/preview", request)).blob();
+    // A newer preview was requested while this one was rendered
+    if (id !== previewRequest) {
+      return;
+    }
+    if (previewURL) {
+      URL.revokeObjectURL(previewURL);
+    }
+    previewURL = URL.createObjectURL(blob);
+    image.src = previewURL;
+    image.hidden = false;
+    message.textContent = "";
+  } catch (err) {
+    if (id !== previewRequest) {
+      return;
+    }
+    image.hidden = true;
+    handleError(err, message);
+  }
+}
+
+async function print(event) {
+  event.preventDefault();
+  const result = $("result");
+  const button = $("print");
+
+  button.disabled = true;
+  result.textContent = "Printing...";
+  result.className = "hint";
+  try {
+    const job = await (await postLabel("/print?wait=true", labelRequest())).json();
+    if (job.state === "failed") {
+      throw new Error(job.error || "printing failed");
+    }
+    result.textContent = `✅ ${job.labels} label(s) printed on ${job.printer}`;
+    result.className = "ok";
+  } catch (err) {
+    handleError(err, result);
+  } finally {
+    button.disabled = false;
+    refresh();
+  }
+}
+
+async function start() {
+  try {
+    presets = await (await api("/presets")).json();
+  } catch (err) {
+    handleError(err, null);
+    return;
+  }
+  renderPresets();
+  started = true;
+  await refresh();
+}
+
+$("login-form").addEventListener("submit", () => {
+  localStorage.setItem(TOKEN_KEY, $("token").value.trim());
+  if (started) {
+    refresh();
+  } else {
+    start();
+  }
+});
+
+$("logout").addEventListener("click", () => {
+  localStorage.removeItem(TOKEN_KEY);
+  login("");
+});
+
+$("text").addEventListener("input", schedulePreview);
+$("copies").addEventListener("input", schedulePreview);
+$("printer").addEventListener("change", schedulePreview);
+$("preset").addEventListener("change", () => {
+  showPresetInfo();
+  schedulePreview();
+});
+$("label-form").addEventListener("submit", print);
+
+setInterval(refresh, REFRESH_INTERVAL_MS);
+start();
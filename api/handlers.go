@@ -0,0 +1,379 @@
+package api
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/history"
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/templates"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Name recorded as the user of jobs received over the API
+const apiUser = "api"
+
+// Body of POST /print and POST /preview
+type labelRequest struct {
+	Text string `json:"text"`
+	// Preset applied to the text, optional
+	Preset string `json:"preset"`
+	// Printer name, defaults to the preset's printer or the default printer
+	Printer string `json:"printer"`
+	// Font size, a number or "auto", overrides the printer or preset
+	FontSize string `json:"font_size"`
+	// Number of copies, overrides the preset
+	Copies int `json:"copies"`
+}
+
+// Job as returned by POST /print and GET /jobs/{id}
+type jobResponse struct {
+	ID        int64     `json:"id"`
+	Printer   string    `json:"printer"`
+	Kind      string    `json:"kind"`
+	State     string    `json:"state"`
+	Text      string    `json:"text"`
+	Labels    int       `json:"labels"`
+	Preset    string    `json:"preset,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Printer as returned by GET /status
+type printerStatus struct {
+	Name        string                `json:"name"`
+	Available   bool                  `json:"available"`
+	Power       *powerStatus          `json:"power,omitempty"`
+	PendingJobs int                   `json:"pending_jobs"`
+	Info        *printers.PrinterInfo `json:"info,omitempty"`
+	Errors      []string              `json:"errors,omitempty"`
+}
+
+// Power state of a printer in GET /status
+type powerStatus struct {
+	Managed       bool       `json:"managed"`
+	On            bool       `json:"on"`
+	ShutdownAt    *time.Time `json:"shutdown_at,omitempty"`
+	HoldUntil     *time.Time `json:"hold_until,omitempty"`
+	OnTimeSeconds int64      `json:"on_time_seconds"`
+	PowerCycles   int        `json:"power_cycles"`
+	AutoShutdowns int        `json:"auto_shutdowns"`
+}
+
+// Preset as returned by GET /presets
+type presetResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	FontSize    string `json:"font_size"`
+	FontFamily  string `json:"font_family,omitempty"`
+	MaxLines    int    `json:"max_lines,omitempty"`
+	Printer     string `json:"printer,omitempty"`
+	Template    string `json:"template,omitempty"`
+	Barcode     string `json:"barcode,omitempty"`
+	Copies      int    `json:"copies,omitempty"`
+}
+
+// History entry as returned by GET /history
+type historyResponse struct {
+	ID      uint64    `json:"id"`
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Printer string    `json:"printer,omitempty"`
+	Preset  string    `json:"preset,omitempty"`
+	Text    string    `json:"text"`
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Number of history entries returned when no count is given, and the most that can be requested
+const (
+	defaultHistoryEntries = 20
+	maxHistoryEntries     = 100
+)
+
+// Error with the HTTP status it should be answered with
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+// Queues a label, ?wait=true waits until it is printed
+func (s *Server) handlePrint(w http.ResponseWriter, r *http.Request) {
+	job, err := s.submit(r, printers.JobPrint)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("wait") != "true" {
+		writeJSON(w, http.StatusAccepted, newJobResponse(job))
+		return
+	}
+
+	if err := job.Wait(r.Context()); err != nil {
+		status := http.StatusInternalServerError
+		if r.Context().Err() != nil {
+			status = http.StatusGatewayTimeout
+		}
+		writeJSON(w, status, newJobResponse(job))
+		return
+	}
+	writeJSON(w, http.StatusOK, newJobResponse(job))
+}
+
+// Renders a label and returns it as PNG
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	job, err := s.submit(r, printers.JobPreview)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	if err := job.Wait(r.Context()); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("X-Job-Id", strconv.FormatInt(job.ID, 10))
+	w.Write(job.Preview())
+}
+
+// Lists all printers with their power state, queue and last known tape
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	statuses := []printerStatus{}
+	for _, name := range s.pool.Names() {
+		status := printerStatus{Name: name}
+		if printer := s.pool.Get(name); printer != nil {
+			status.Available = true
+			status.Power = newPowerStatus(printer.Power().Stats())
+			status.PendingJobs = printer.PendingJobs()
+			if info := printer.LastInfo(); info != nil {
+				status.Info = info
+				status.Errors = info.Errors.Messages()
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// Lists the configured presets
+func (s *Server) handlePresets(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Get()
+	presets := []presetResponse{}
+	for _, name := range cfg.Printer.GetPresetNames() {
+		preset := cfg.Printer.GetPreset(name)
+		presets = append(presets, presetResponse{
+			Name:        name,
+			Description: preset.Description,
+			FontSize:    preset.FontSize.String(),
+			FontFamily:  preset.FontFamily,
+			MaxLines:    preset.MaxLines,
+			Printer:     preset.Printer,
+			Template:    preset.Template,
+			Barcode:     preset.Barcode,
+			Copies:      preset.Copies,
+		})
+	}
+	writeJSON(w, http.StatusOK, presets)
+}
+
+// Returns a job of any printer by its ID
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid job id '%s'", r.PathValue("id")))
+		return
+	}
+
+	job := s.pool.GetJob(id)
+	if job == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %d not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, newJobResponse(job))
+}
+
+// Lists the most recently printed labels of all users, ?count=N limits the number
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("print history is not available"))
+		return
+	}
+
+	count := defaultHistoryEntries
+	if value := r.URL.Query().Get("count"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid count '%s'", value))
+			return
+		}
+		count = min(n, maxHistoryEntries)
+	}
+
+	entries, err := s.history.Latest(count)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := make([]historyResponse, 0, len(entries))
+	for _, entry := range entries {
+		user := entry.Username
+		if user == "" {
+			user = strconv.FormatInt(entry.UserID, 10)
+		}
+		response = append(response, historyResponse{
+			ID:      entry.ID,
+			Time:    entry.Timestamp,
+			User:    user,
+			Printer: entry.Printer,
+			Preset:  entry.Preset,
+			Text:    entry.DisplayText(),
+			Outcome: entry.Outcome,
+			Error:   entry.Error,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Decodes the label request and submits it to the chosen printer
+func (s *Server) submit(r *http.Request, kind printers.JobKind) (*printers.Job, error) {
+	var request labelRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, &requestError{http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err)}
+	}
+
+	var preset *config.Preset
+	if request.Preset != "" {
+		preset = config.Get().Printer.GetPreset(request.Preset)
+		if preset == nil {
+			return nil, &requestError{http.StatusNotFound, fmt.Errorf("preset '%s' not found", request.Preset)}
+		}
+	}
+
+	name := request.Printer
+	if name == "" && preset != nil {
+		name = preset.Printer
+	}
+	if name == "" {
+		name = s.pool.DefaultName()
+	}
+	printer := s.pool.Get(name)
+	if printer == nil {
+		return nil, &requestError{http.StatusServiceUnavailable, fmt.Errorf("printer '%s' is not available", name)}
+	}
+
+	counter := s.history.ReserveCounter(request.Preset, kind == printers.JobPreview)
+	label, err := s.label(printer, request, preset, counter)
+	if err != nil {
+		counter.Release()
+		return nil, &requestError{http.StatusBadRequest, fmt.Errorf("invalid label: %v", err)}
+	}
+
+	logger.Info("HTTP API %s on '%s' from %s: %s", kind, printer.Name(), r.RemoteAddr, label.DisplayText())
+	job, err := printer.Submit(kind, label, printers.JobMeta{Username: apiUser, Preset: request.Preset, Counter: counter.Value()})
+	if err != nil {
+		counter.Release()
+	}
+	if errors.Is(err, printers.ErrQueueFull) || errors.Is(err, printers.ErrPrinterClosed) {
+		return nil, &requestError{http.StatusServiceUnavailable, err}
+	}
+	return job, err
+}
+
+// Builds the label of a request from the printer defaults or the preset and the overrides
+// A preset template takes its counter value from the reservation
+func (s *Server) label(printer *printers.Printer, request labelRequest, preset *config.Preset, counter *history.Reservation) (printers.Label, error) {
+	var label printers.Label
+	var err error
+	maxLines := printer.Config().MaxLines
+	if preset != nil {
+		maxLines = preset.MaxLines
+		label, err = printer.TemplatePresetLabel(preset, templates.Data{
+			Text:        strings.TrimSpace(request.Text),
+			User:        apiUser,
+			Preset:      request.Preset,
+			NextCounter: counter.Next,
+		})
+	} else {
+		label, err = printer.DefaultLabel(request.Text)
+	}
+	if err != nil {
+		return label, err
+	}
+
+	if request.FontSize == "" && request.Copies == 0 {
+		return label, nil
+	}
+	if request.FontSize != "" {
+		fontSize, err := config.ParseFontSize(request.FontSize)
+		if err != nil {
+			return label, err
+		}
+		label.FontSize = int(fontSize)
+	}
+	if request.Copies != 0 {
+		if request.Copies < 1 || request.Copies > printers.MaxChainLabels {
+			return label, fmt.Errorf("copies must be between 1 and %d", printers.MaxChainLabels)
+		}
+		label.Copies = request.Copies
+	}
+	return label, label.Validate(maxLines)
+}
+
+// Converts a job for the JSON response
+func newJobResponse(job *printers.Job) jobResponse {
+	response := jobResponse{
+		ID:        job.ID,
+		Printer:   job.Printer,
+		Kind:      string(job.Kind),
+		State:     string(job.State()),
+		Text:      job.Label.DisplayText(),
+		Labels:    job.Label.Total(),
+		Preset:    job.Meta.Preset,
+		CreatedAt: job.CreatedAt,
+	}
+	if err := job.Err(); err != nil {
+		response.Error = err.Error()
+	}
+	return response
+}
+
+// Converts power stats for the JSON response
+func newPowerStatus(stats printers.PowerStats) *powerStatus {
+	status := &powerStatus{
+		Managed:       stats.Managed,
+		On:            stats.On,
+		OnTimeSeconds: int64(stats.OnTime.Seconds()),
+		PowerCycles:   stats.PowerCycles,
+		AutoShutdowns: stats.AutoShutdowns,
+	}
+	if !stats.ShutdownAt.IsZero() {
+		status.ShutdownAt = &stats.ShutdownAt
+	}
+	if !stats.HoldUntil.IsZero() {
+		status.HoldUntil = &stats.HoldUntil
+	}
+	return status
+}
+
+// Answers with the status of a requestError, or 500 for other errors
+func writeRequestError(w http.ResponseWriter, err error) {
+	var requestErr *requestError
+	if errors.As(err, &requestErr) {
+		writeError(w, requestErr.status, requestErr.err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/history"
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Largest request body accepted, enough for any label text
+const maxBodyBytes = 1 << 20
+
+// Time given to running requests when the server shuts down
+const shutdownTimeout = 5 * time.Second
+
+// Local HTTP API printing through the same printers as the bot
+type Server struct {
+	ctx       context.Context
+	pool      *printers.Pool
+	history   *history.Store
+	token     string
+	dashboard bool
+	server    *http.Server
+}
+
+// Starts the HTTP server in the background
+// Fails if API_TOKEN is not set or the address cannot be used
+// store may be nil, presets with counters then fail
+func Start(ctx context.Context, cfg *config.APIConfig, pool *printers.Pool, store *history.Store) (*Server, error) {
+	token := strings.TrimSpace(os.Getenv("API_TOKEN"))
+	if token == "" {
+		return nil, fmt.Errorf("API_TOKEN environment variable is required for the HTTP API")
+	}
+
+	s := &Server{
+		ctx:       ctx,
+		pool:      pool,
+		history:   store,
+		token:     token,
+		dashboard: cfg.Dashboard,
+	}
+
+	s.server = &http.Server{
+		Addr:              cfg.GetListen(),
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", s.server.Addr, err)
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("HTTP API stopped: %v", err)
+		}
+	}()
+
+	logger.Info("HTTP API listening on %s", listener.Addr())
+	if s.dashboard {
+		logger.Info("Web dashboard available on http://%s/", listener.Addr())
+	}
+	return s, nil
+}
+
+// Stops the server, letting running requests finish for a moment
+func (s *Server) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		logger.Error("Error stopping HTTP API: %v", err)
+	}
+	logger.Info("HTTP API stopped")
+}
+
+// Registers the endpoints, all of them require the token
+// The dashboard files are public, the dashboard asks for the token to call the endpoints
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("POST /print", s.authorize(s.handlePrint))
+	mux.Handle("POST /preview", s.authorize(s.handlePreview))
+	mux.Handle("GET /status", s.authorize(s.handleStatus))
+	mux.Handle("GET /presets", s.authorize(s.handlePresets))
+	mux.Handle("GET /jobs/{id}", s.authorize(s.handleJob))
+	mux.Handle("GET /history", s.authorize(s.handleHistory))
+	if s.dashboard {
+		mux.Handle("GET /", dashboardHandler())
+	}
+	return mux
+}
+
+// Rejects requests without the bearer token
+func (s *Server) authorize(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			logger.Warn("Rejected HTTP API request from %s: missing or invalid token", r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid token"))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Writes the value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logger.Error("Failed to write HTTP API response: %v", err)
+	}
+}
+
+// Writes an error as {"error": "..."}
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/gpio"
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Name recorded as the user of jobs started from the command line
+const cliUser = "cli"
+
+// Subcommand of the binary, the bot runs when none is given
+type cliCommand struct {
+	Name        string
+	Usage       string
+	Description string
+	Run         func(args []string) error
+}
+
+// Registry of all subcommands, in the order they are listed in the help
+var cliCommands []cliCommand
+
+func init() {
+	cliCommands = []cliCommand{
+		{"print", "print [flags] <text>", "Print a label", printCommand},
+		{"preview", "preview [flags] -o <file.png> <text>", "Render a label to a PNG file without printing", previewCommand},
+		{"status", "status [flags]", "Show power state, tape and errors of the printers", statusCommand},
+		{"power", "power [flags] on|off", "Switch a printer's relay on or off", powerCommand},
+		{"presets", "presets [flags]", "List the configured presets", presetsCommand},
+		{"config", "config [flags] validate", "Check the configuration file", configCommand},
+	}
+}
+
+// Options shared by all subcommands
+type cliOptions struct {
+	configPath string
+	verbose    bool
+}
+
+// Runs the subcommand in args and returns the exit code
+func runCLI(args []string) int {
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		printCLIUsage()
+		return 0
+	}
+
+	for _, command := range cliCommands {
+		if command.Name != name {
+			continue
+		}
+
+		err := command.Run(args[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n", name)
+	printCLIUsage()
+	return 2
+}
+
+// Prints the list of subcommands
+func printCLIUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Without a command the Telegram bot is started.")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, command := range cliCommands {
+		fmt.Fprintf(os.Stderr, "  %-40s %s\n", command.Usage, command.Description)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' to see the flags of a command.\n", os.Args[0])
+}
+
+// Creates the flag set of a subcommand with the shared -config and -v flags
+func newFlagSet(command string, options *cliOptions) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.StringVar(&options.configPath, "config", "", "path of the config file (default: config.yaml next to the binary or in the working directory)")
+	flags.BoolVar(&options.verbose, "v", false, "show the log messages of the configured log level")
+	flags.Usage = func() {
+		for _, c := range cliCommands {
+			if c.Name == command {
+				fmt.Fprintf(flags.Output(), "Usage: %s %s\n\n%s\n\nFlags:\n", os.Args[0], c.Usage, c.Description)
+			}
+		}
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// Loads the configuration and sets the log level
+// Only warnings and errors are logged unless -v is given, so the command output stays readable
+func loadCLIConfig(options *cliOptions) (*config.Config, error) {
+	var err error
+	if options.configPath != "" {
+		err = config.Load(options.configPath)
+	} else {
+		err = config.LoadDefault()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.Get()
+	if options.verbose {
+		logger.SetLogLevel(cfg.Logging.GetLogLevel())
+	} else {
+		logger.SetLogLevel(logger.WARN)
+	}
+	return cfg, nil
+}
+
+// Returns the configuration of the named printer, or of the default printer if name is empty
+func cliPrinterConfig(cfg *config.Config, name string) (*config.NamedPrinterConfig, error) {
+	if name == "" {
+		name = cfg.Routing.Default
+	}
+	if name == "" {
+		return &cfg.Printers[0], nil
+	}
+
+	printerCfg := cfg.GetPrinter(name)
+	if printerCfg == nil {
+		return nil, fmt.Errorf("printer '%s' is not configured", name)
+	}
+	return printerCfg, nil
+}
+
+// Opens the relay of a printer without applying its startup state
+// The service may be running and using the printer, so the relay is left as it is
+func openCLISwitch(gpioCfg *config.GPIOConfig) (gpio.Switch, error) {
+	keep := *gpioCfg
+	keep.StartupState = config.GPIOStartupKeep
+
+	relay, err := gpio.NewSwitch(&keep)
+	if err != nil {
+		if gpioCfg.Driver == config.GPIODriverChardev {
+			return nil, fmt.Errorf("%v (the line can only be used by one process, is the service running?)", err)
+		}
+		return nil, err
+	}
+	return relay, nil
+}
+
+// Relay that is not switched off when the command ends if it was on before
+// A powered printer may be in use by the service, switching it off would break its jobs
+type sharedSwitch struct {
+	gpio.Switch
+	wasOn bool
+}
+
+func (s *sharedSwitch) TurnOff() error {
+	if s.wasOn {
+		return nil
+	}
+	return s.Switch.TurnOff()
+}
+
+func (s *sharedSwitch) Close() error {
+	if s.wasOn {
+		return nil
+	}
+	return s.Switch.Close()
+}
+
+// Creates a printer for a command, powering it through its relay if needed
+// The returned function closes the printer, a printer that was off is switched off again
+// Without a usable relay the printer is assumed to be powered
+func openCLIPrinter(ctx context.Context, printerCfg *config.NamedPrinterConfig) (*printers.Printer, func(), error) {
+	var relay gpio.Switch
+	if printerCfg.GPIO != nil {
+		var err error
+		relay, err = openCLISwitch(printerCfg.GPIO)
+		if err != nil {
+			logger.Warn("Relay of printer '%s' is not usable, assuming the printer is on: %v", printerCfg.Name, err)
+		}
+	}
+	return newCLIPrinter(ctx, printerCfg, relay)
+}
+
+// Creates a printer for a command with an opened relay, which may be nil
+func newCLIPrinter(ctx context.Context, printerCfg *config.NamedPrinterConfig, raw gpio.Switch) (*printers.Printer, func(), error) {
+	var relay gpio.Switch
+	if raw != nil {
+		relay = &sharedSwitch{Switch: raw, wasOn: raw.GetState()}
+	}
+
+	printer := printers.NewPrinter(ctx, printerCfg.Name, &printerCfg.PrinterConfig, relay)
+	if printer == nil {
+		if relay != nil {
+			relay.Close()
+		}
+		return nil, nil, fmt.Errorf("printer '%s' is not available", printerCfg.Name)
+	}
+
+	closePrinter := func() {
+		if err := printer.Close(); err != nil {
+			logger.Warn("Failed to close printer '%s': %v", printerCfg.Name, err)
+		}
+		if relay != nil {
+			relay.Close()
+		}
+	}
+	return printer, closePrinter, nil
+}
+
+// Parses the flags, which may also follow the other arguments, and returns the other arguments
+// Everything after "--" is taken as is, e.g. for words of the label text starting with a dash
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		remaining := flags.Args()
+		if consumed := len(args) - len(remaining); consumed > 0 && args[consumed-1] == "--" {
+			return append(rest, remaining...), nil
+		}
+		if len(remaining) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, remaining[0])
+		args = remaining[1:]
+	}
+}
+
+// Joins the arguments to the label text
+func argsText(args []string) string {
+	return strings.TrimSpace(strings.Join(args, " "))
+}
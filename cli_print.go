@@ -0,0 +1,180 @@
+package main
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/history"
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/templates"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// Label flags of the print and preview commands
+type labelOptions struct {
+	printer  string
+	preset   string
+	fontSize string
+	copies   int
+}
+
+// Adds the label flags to a flag set
+func (o *labelOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.printer, "printer", "", "printer to use (default: the preset's printer or the default printer)")
+	flags.StringVar(&o.preset, "preset", "", "preset applied to the text")
+	flags.StringVar(&o.fontSize, "size", "", "font size, a number or 'auto' (default: the printer's or preset's size)")
+	flags.IntVar(&o.copies, "copies", 0, "number of copies (default: the preset's copies or 1)")
+}
+
+func printCommand(args []string) error {
+	var options cliOptions
+	var labelOpts labelOptions
+	flags := newFlagSet("print", &options)
+	labelOpts.register(flags)
+	rest, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	job, err := runCLIJob(&options, &labelOpts, argsText(rest), printers.JobPrint)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ %d label(s) printed on '%s': %s\n", job.Label.Total(), job.Printer, job.Label.DisplayText())
+	return nil
+}
+
+func previewCommand(args []string) error {
+	var options cliOptions
+	var labelOpts labelOptions
+	flags := newFlagSet("preview", &options)
+	labelOpts.register(flags)
+	output := flags.String("o", "preview.png", "PNG file the preview is written to")
+	rest, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	job, err := runCLIJob(&options, &labelOpts, argsText(rest), printers.JobPreview)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(*output, job.Preview(), 0644); err != nil {
+		return fmt.Errorf("failed to write preview: %v", err)
+	}
+	fmt.Printf("✅ Preview of '%s' written to %s\n", job.Label.DisplayText(), *output)
+	return nil
+}
+
+// Builds the label and runs it as a job on the chosen printer, waiting until it is done
+func runCLIJob(options *cliOptions, labelOpts *labelOptions, text string, kind printers.JobKind) (*printers.Job, error) {
+	cfg, err := loadCLIConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return nil, fmt.Errorf("missing text of the label")
+	}
+
+	var preset *config.Preset
+	if labelOpts.preset != "" {
+		preset = cfg.Printer.GetPreset(labelOpts.preset)
+		if preset == nil {
+			return nil, fmt.Errorf("preset '%s' not found, run 'presets' to list them", labelOpts.preset)
+		}
+	}
+
+	name := labelOpts.printer
+	if name == "" && preset != nil {
+		name = preset.Printer
+	}
+	printerCfg, err := cliPrinterConfig(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	// Counters of preset templates are kept in the history
+	store := openCLIHistory(cfg)
+	if store != nil {
+		defer store.Close()
+	}
+
+	printer, closePrinter, err := openCLIPrinter(ctx, printerCfg)
+	if err != nil {
+		return nil, err
+	}
+	defer closePrinter()
+	if store != nil {
+		printer.OnJobFinished(store.RecordJob)
+	}
+
+	counter := store.ReserveCounter(labelOpts.preset, kind == printers.JobPreview)
+	label, err := cliLabel(printer, labelOpts, preset, text, counter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label: %v", err)
+	}
+
+	job, err := printer.Submit(kind, label, printers.JobMeta{Username: cliUser, Preset: labelOpts.preset, Counter: counter.Value()})
+	if err != nil {
+		return nil, err
+	}
+	if err := job.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Builds the label from the printer defaults or the preset and the flags
+// A preset template takes its counter value from the reservation
+func cliLabel(printer *printers.Printer, labelOpts *labelOptions, preset *config.Preset, text string, counter *history.Reservation) (printers.Label, error) {
+	var label printers.Label
+	var err error
+	maxLines := printer.Config().MaxLines
+	if preset != nil {
+		maxLines = preset.MaxLines
+		label, err = printer.TemplatePresetLabel(preset, templates.Data{
+			Text:        text,
+			User:        cliUser,
+			Preset:      labelOpts.preset,
+			NextCounter: counter.Next,
+		})
+	} else {
+		label, err = printer.DefaultLabel(text)
+	}
+	if err != nil {
+		return label, err
+	}
+
+	if labelOpts.fontSize == "" && labelOpts.copies == 0 {
+		return label, nil
+	}
+	if labelOpts.fontSize != "" {
+		fontSize, err := config.ParseFontSize(labelOpts.fontSize)
+		if err != nil {
+			return label, err
+		}
+		label.FontSize = int(fontSize)
+	}
+	if labelOpts.copies != 0 {
+		label.Copies = labelOpts.copies
+	}
+	return label, label.Validate(maxLines)
+}
+
+// Opens the print history, or returns nil if it is not available, e.g. while the service holds it
+func openCLIHistory(cfg *config.Config) *history.Store {
+	store, err := history.Open(cfg.History.Path, cfg.Printer.GetFolderPermissions())
+	if err != nil {
+		logger.Warn("Print history is not available, the job is not recorded: %v", err)
+		return nil
+	}
+	return store
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/gpio"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/utils"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+func statusCommand(args []string) error {
+	var options cliOptions
+	flags := newFlagSet("status", &options)
+	name := flags.String("printer", "", "only show this printer")
+	wake := flags.Bool("wake", false, "switch printers that are off on to query them, and off again afterwards")
+	rest, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument '%s'", rest[0])
+	}
+
+	cfg, err := loadCLIConfig(&options)
+	if err != nil {
+		return err
+	}
+
+	printerCfgs := cfg.Printers
+	if *name != "" {
+		printerCfg := cfg.GetPrinter(*name)
+		if printerCfg == nil {
+			return fmt.Errorf("printer '%s' is not configured", *name)
+		}
+		printerCfgs = []config.NamedPrinterConfig{*printerCfg}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	for i := range printerCfgs {
+		if i > 0 {
+			fmt.Println()
+		}
+		printStatus(ctx, &printerCfgs[i], *wake)
+	}
+	return nil
+}
+
+// Prints the power state and, if the printer is powered, its tape and errors
+func printStatus(ctx context.Context, printerCfg *config.NamedPrinterConfig, wake bool) {
+	fmt.Printf("🖨️ %s (%s backend)\n", printerCfg.Name, printerCfg.GetBackend())
+
+	var relay gpio.Switch
+	if printerCfg.GPIO == nil {
+		fmt.Println("🔌 Power: always on (no power switch)")
+	} else {
+		var err error
+		relay, err = openCLISwitch(printerCfg.GPIO)
+		switch {
+		case err != nil:
+			fmt.Printf("🔌 Power: unknown, %s: %v\n", printerCfg.GPIO, err)
+		case relay.GetState():
+			fmt.Printf("🔌 Power: on (%s)\n", printerCfg.GPIO)
+		default:
+			fmt.Printf("🔌 Power: off (%s)\n", printerCfg.GPIO)
+			if !wake {
+				relay.Close()
+				fmt.Println("   Run 'status -wake' or 'power on' to query the printer")
+				return
+			}
+		}
+	}
+
+	printer, closePrinter, err := newCLIPrinter(ctx, printerCfg, relay)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	defer closePrinter()
+
+	info := printer.LastInfo()
+	if info == nil {
+		fmt.Println("❌ No printer info available")
+		return
+	}
+	fmt.Print(formatCLIPrinterInfo(info))
+}
+
+// Formats the printer info like the bot's /status
+func formatCLIPrinterInfo(info *printers.PrinterInfo) string {
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("🏷️ Model: %s\n", utils.ValueOrUnknown(info.Model)))
+	message.WriteString(fmt.Sprintf("📏 Tape: %dmm %s\n", info.TapeWidthMM, utils.ValueOrUnknown(info.MediaType)))
+	message.WriteString(fmt.Sprintf("🎨 Colors: %s text on %s tape\n", utils.ValueOrUnknown(info.TextColor), utils.ValueOrUnknown(info.TapeColor)))
+	message.WriteString(fmt.Sprintf("🔢 Printable height: %dpx\n", info.TapeMaxPixels))
+	if info.HasErrors() {
+		message.WriteString(fmt.Sprintf("⚠️ Errors: %s\n", strings.Join(info.Errors.Messages(), ", ")))
+	}
+	return message.String()
+}
+
+func powerCommand(args []string) error {
+	var options cliOptions
+	flags := newFlagSet("power", &options)
+	name := flags.String("printer", "", "printer to switch (default: the default printer)")
+	rest, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 1 || (rest[0] != "on" && rest[0] != "off") {
+		flags.Usage()
+		return fmt.Errorf("expected 'on' or 'off'")
+	}
+	on := rest[0] == "on"
+
+	cfg, err := loadCLIConfig(&options)
+	if err != nil {
+		return err
+	}
+	printerCfg, err := cliPrinterConfig(cfg, *name)
+	if err != nil {
+		return err
+	}
+	if printerCfg.GPIO == nil {
+		return fmt.Errorf("printer '%s' has no power switch and is always on", printerCfg.Name)
+	}
+
+	relay, err := openCLISwitch(printerCfg.GPIO)
+	if err != nil {
+		return fmt.Errorf("failed to open relay of printer '%s': %v", printerCfg.Name, err)
+	}
+
+	// The relay is not closed, that would switch the printer off again
+	if on {
+		if err := relay.TurnOn(); err != nil {
+			return err
+		}
+		fmt.Printf("✅ Printer '%s' switched on, the service switches it off after its next job\n", printerCfg.Name)
+		return nil
+	}
+
+	if err := relay.TurnOff(); err != nil {
+		return err
+	}
+	relay.Close()
+	fmt.Printf("✅ Printer '%s' switched off\n", printerCfg.Name)
+	return nil
+}
+
+func presetsCommand(args []string) error {
+	var options cliOptions
+	flags := newFlagSet("presets", &options)
+	rest, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument '%s'", rest[0])
+	}
+
+	cfg, err := loadCLIConfig(&options)
+	if err != nil {
+		return err
+	}
+
+	names := cfg.Printer.GetPresetNames()
+	if len(names) == 0 {
+		fmt.Println("No presets are configured.")
+		return nil
+	}
+
+	for _, name := range names {
+		preset := cfg.Printer.GetPreset(name)
+		settings := []string{"font size: " + preset.FontSize.String()}
+		if preset.FontFamily != "" {
+			settings = append(settings, "font: "+preset.FontFamily)
+		}
+		if preset.Barcode != "" {
+			settings = append(settings, "barcode: "+preset.Barcode)
+		}
+		if preset.Series != nil {
+			settings = append(settings, fmt.Sprintf("series of %d", preset.Series.Count))
+		}
+		if preset.Copies > 1 {
+			settings = append(settings, fmt.Sprintf("%d copies", preset.Copies))
+		}
+		if preset.Printer != "" {
+			settings = append(settings, "printer: "+preset.Printer)
+		}
+
+		fmt.Printf("• %s - %s (%s)\n", name, preset.Description, strings.Join(settings, ", "))
+		if preset.Template != "" {
+			fmt.Printf("   template: %s\n", preset.Template)
+		}
+	}
+	return nil
+}
+
+func configCommand(args []string) error {
+	var options cliOptions
+	flags := newFlagSet("config", &options)
+	rest, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 1 || rest[0] != "validate" {
+		flags.Usage()
+		return fmt.Errorf("expected 'validate'")
+	}
+
+	cfg, err := loadCLIConfig(&options)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %v", err)
+	}
+
+	fmt.Println("✅ Configuration is valid")
+	for _, printerCfg := range cfg.Printers {
+		power := "always on"
+		if printerCfg.GPIO != nil {
+			power = printerCfg.GPIO.String()
+		}
+		fmt.Printf("🖨️ %s: %s backend, power: %s\n", printerCfg.Name, printerCfg.GetBackend(), power)
+	}
+	fmt.Printf("📋 Presets: %d\n", len(cfg.Printer.GetPresetNames()))
+	if cfg.MQTT.Enabled {
+		fmt.Printf("📡 MQTT: %s\n", cfg.MQTT.Broker)
+	}
+	if cfg.API.Enabled {
+		fmt.Printf("🌐 HTTP API: %s\n", cfg.API.GetListen())
+	}
+	if cfg.Metrics.Enabled {
+		fmt.Printf("📈 Metrics: %s\n", cfg.Metrics.GetListen())
+	}
+
+	// Secrets are not part of the file, but the service needs them
+	missing := []string{}
+	for _, name := range requiredEnv(cfg) {
+		if strings.TrimSpace(os.Getenv(name)) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Printf("⚠️ Environment variables not set: %s\n", strings.Join(missing, ", "))
+	}
+	if os.Getenv("TELEGRAM_ALLOWED_CHAT_IDS") == "" {
+		fmt.Println("⚠️ TELEGRAM_ALLOWED_CHAT_IDS is not set, every chat can use the bot")
+	}
+	return nil
+}
+
+// Returns the environment variables the service needs with this configuration
+func requiredEnv(cfg *config.Config) []string {
+	names := []string{"TELEGRAM_BOT_TOKEN"}
+	if cfg.API.Enabled {
+		names = append(names, "API_TOKEN")
+	}
+	return names
+}
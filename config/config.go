@@ -2,9 +2,11 @@ package config
 
 import (
 	"brother-cube-telegram/logger"
+	"brother-cube-telegram/templates"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,20 +18,197 @@ type Config struct {
 	Printer PrinterConfig `yaml:"printer"`
 	GPIO    GPIOConfig    `yaml:"gpio"`
 	Logging LoggingConfig `yaml:"logging"`
+	History HistoryConfig `yaml:"history"`
+	Routing RoutingConfig `yaml:"routing"`
+	MQTT    MQTTConfig    `yaml:"mqtt"`
+	API     APIConfig     `yaml:"api"`
+	Metrics MetricsConfig `yaml:"metrics"`
+
+	// Named printers, filled by Load from the "printers" list
+	// Holds a single printer named DefaultPrinterName built from the printer and gpio sections if no list is given
+	Printers []NamedPrinterConfig `yaml:"-"`
+}
+
+// Name of the printer used when no printers list is configured
+const DefaultPrinterName = "default"
+
+// A printer with its own backend, relay and defaults
+// Settings not given for the printer are taken from the printer section
+type NamedPrinterConfig struct {
+	// Name used for routing and the /printer command
+	Name string `yaml:"name"`
+
+	// Relay powering this printer, nil if it has none
+	GPIO *GPIOConfig `yaml:"gpio"`
+
+	PrinterConfig `yaml:",inline"`
+}
+
+// Decides which printer handles a label
+type RoutingConfig struct {
+	// Printer used when no other rule applies (default: the first printer)
+	Default string `yaml:"default"`
+
+	// Printer per Telegram chat ID
+	Chats map[int64]string `yaml:"chats"`
+}
+
+// Font size for labels, or AutoFontSize to fit the loaded tape
+type FontSize int
+
+// Picks the largest font size that fits the tape for the number of lines
+const AutoFontSize FontSize = 0
+
+// Returns true if the font size is picked automatically
+func (f FontSize) IsAuto() bool {
+	return f == AutoFontSize
+}
+
+// Returns the font size as a number, or "auto"
+func (f FontSize) String() string {
+	if f.IsAuto() {
+		return "auto"
+	}
+	return strconv.Itoa(int(f))
+}
+
+// Parses a font size from a number or "auto"
+func ParseFontSize(value string) (FontSize, error) {
+	if strings.EqualFold(strings.TrimSpace(value), "auto") {
+		return AutoFontSize, nil
+	}
+
+	size, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || size <= 0 {
+		return AutoFontSize, fmt.Errorf("invalid font size '%s': must be a positive number or \"auto\"", value)
+	}
+	return FontSize(size), nil
+}
+
+// Allows font sizes to be written as numbers or "auto" in the YAML file
+func (f *FontSize) UnmarshalYAML(value *yaml.Node) error {
+	size, err := ParseFontSize(value.Value)
+	if err != nil {
+		return err
+	}
+	*f = size
+	return nil
 }
 
 // Preset holds configuration for a specific printing preset
 type Preset struct {
-	// Font size for this preset
-	FontSize int `yaml:"font_size"`
+	// Font size for this preset, a number or "auto"
+	FontSize FontSize `yaml:"font_size"`
 	// Font family/file for this preset
 	FontFamily string `yaml:"font_family"`
+	// Maximum number of stacked lines (0 for the printer limit of 4)
+	MaxLines int `yaml:"max_lines"`
+	// Printer that prints this preset, empty to use the printer of the chat
+	Printer string `yaml:"printer"`
+	// Go text/template rendering the label text, e.g. "{{.Text}} — opened {{today}}"
+	Template string `yaml:"template"`
+	// Print the text as a barcode of this type (code128, ean13, code39) instead of as text
+	Barcode string `yaml:"barcode"`
+	// Number of copies printed of each label (0 or 1 for a single one)
+	Copies int `yaml:"copies"`
+	// Prints a numbered series instead of a single label
+	Series *SeriesConfig `yaml:"series"`
+	// Placement on the tape, replaces the printer's default layout if set
+	Layout *LayoutConfig `yaml:"layout"`
 	// Description of the preset
 	Description string `yaml:"description"`
 }
 
+// Describes a numbered series of labels, "{n}" in the text is replaced by the number
+type SeriesConfig struct {
+	// First number of the series (default: 1)
+	Start int `yaml:"start"`
+	// Number of labels in the series
+	Count int `yaml:"count"`
+	// Minimum number of digits, numbers are padded with zeros
+	Padding int `yaml:"padding"`
+}
+
+// Describes how labels are placed on the tape
+type LayoutConfig struct {
+	// Skip the tape feed after the last label so the next job continues right after it
+	Chain bool `yaml:"chain"`
+	// Where cut marks are printed: "none", "between" (default) or "around"
+	CutMarks string `yaml:"cut_marks"`
+	// Blank tape before the content of each label in millimeters
+	LeadingMM float64 `yaml:"leading_mm"`
+	// Blank tape after the content of each label in millimeters
+	TrailingMM float64 `yaml:"trailing_mm"`
+	// Minimum length of each label in millimeters
+	MinLengthMM float64 `yaml:"min_length_mm"`
+}
+
+// Checks the cut mark mode and that all lengths are positive
+func (l *LayoutConfig) Validate() error {
+	switch l.CutMarks {
+	case "", "none", "between", "around":
+	default:
+		return fmt.Errorf("unknown cut mark mode '%s', expected none, between or around", l.CutMarks)
+	}
+	if l.LeadingMM < 0 || l.TrailingMM < 0 || l.MinLengthMM < 0 {
+		return fmt.Errorf("label padding and length must not be negative")
+	}
+	return nil
+}
+
+// Supported linear barcode symbologies
+const (
+	BarcodeCode128 = "code128"
+	BarcodeEAN13   = "ean13"
+	BarcodeCode39  = "code39"
+)
+
+// Returns the list of supported barcode types
+func BarcodeTypes() []string {
+	return []string{BarcodeCode128, BarcodeEAN13, BarcodeCode39}
+}
+
+// Normalizes user input like "EAN-13" or "Code-128" to a barcode type
+// Returns false if the type is not supported
+func ParseBarcodeType(name string) (string, bool) {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(name))
+	switch normalized {
+	case BarcodeCode128, "128":
+		return BarcodeCode128, true
+	case BarcodeEAN13, "ean":
+		return BarcodeEAN13, true
+	case BarcodeCode39, "39":
+		return BarcodeCode39, true
+	}
+	return "", false
+}
+
+// Names of the supported printer backends
+const (
+	BackendPtouch = "ptouch"
+	BackendFile   = "file"
+)
+
+// Holds configuration for the file printer backend
+type FileBackendConfig struct {
+	// Folder where printed labels are written as PNG files
+	OutputFolder string `yaml:"output_folder"`
+
+	// Printable height of the simulated tape in pixels
+	PrintHeight int `yaml:"print_height"`
+
+	// Width of the simulated tape in millimeters
+	TapeWidthMM int `yaml:"tape_width_mm"`
+}
+
 // Holds printer-specific configuration
 type PrinterConfig struct {
+	// Backend used to produce labels: "ptouch" (default) or "file"
+	Backend string `yaml:"backend"`
+
+	// Settings for the file backend
+	FileBackend FileBackendConfig `yaml:"file_backend"`
+
 	// Number of retry attempts when connecting to printer
 	RetryAttempts int `yaml:"retry_attempts"`
 
@@ -39,12 +218,30 @@ type PrinterConfig struct {
 	// Path to store draft/preview images
 	DraftsFolder string `yaml:"drafts_folder"`
 
-	// Font size for label printing
-	FontSize int `yaml:"font_size"`
+	// Font size for label printing, a number or "auto" (default)
+	FontSize FontSize `yaml:"font_size"`
+
+	// Maximum number of stacked lines for labels without a preset (0 for the printer limit of 4)
+	MaxLines int `yaml:"max_lines"`
+
+	// Default placement of labels on the tape
+	Layout LayoutConfig `yaml:"layout"`
+
+	// Go time layout for dates in preset templates (default: 02.01.2006)
+	DateFormat string `yaml:"date_format"`
+
+	// Background health monitoring while the printer is powered
+	Monitor MonitorConfig `yaml:"monitor"`
+
+	// Send a preview for images instead of printing them right away
+	ImagePreviewFirst bool `yaml:"image_preview_first"`
 
 	// Base delay in seconds for retries
 	RetryBaseDelaySeconds int `yaml:"retry_base_delay_seconds"`
 
+	// Seconds a single ptouch-print invocation may take before it is killed (default: 30)
+	CommandTimeoutSeconds int `yaml:"command_timeout_seconds"`
+
 	// File permissions for created directories (octal)
 	FolderPermissions int `yaml:"folder_permissions"`
 
@@ -52,10 +249,159 @@ type PrinterConfig struct {
 	Presets map[string]Preset `yaml:"presets"`
 }
 
+// MonitorConfig holds printer health monitoring configuration
+type MonitorConfig struct {
+	// Seconds between status polls, 0 disables monitoring
+	IntervalSeconds int `yaml:"interval_seconds"`
+
+	// Tape width the printer should have loaded, 0 to accept any tape
+	ExpectedTapeWidthMM int `yaml:"expected_tape_width_mm"`
+}
+
+// Names of the supported GPIO drivers
+const (
+	GPIODriverPeriph    = "periph"
+	GPIODriverChardev   = "chardev"
+	GPIODriverSimulated = "simulated"
+	GPIODriverTasmota   = "tasmota"
+	GPIODriverShelly    = "shelly"
+	GPIODriverShellyRPC = "shelly_rpc"
+)
+
 // GPIOConfig holds GPIO-specific configuration
 type GPIOConfig struct {
-	// GPIO pin number for the relay
+	// Driver used to switch the relay: "periph" (default), "chardev", "simulated",
+	// or a smart plug: "tasmota", "shelly" (Gen1) or "shelly_rpc" (Gen2 and later)
+	Driver string `yaml:"driver"`
+
+	// GPIO chip of the chardev driver, a name like "gpiochip0" or a path (default: gpiochip0)
+	Chip string `yaml:"chip"`
+
+	// GPIO pin number for the relay, the line offset on the chip for the chardev driver
 	RelayPin int `yaml:"relay_pin"`
+
+	// Level that switches the relay on: "active_low" (default) or "active_high"
+	Polarity string `yaml:"polarity"`
+
+	// What to do with the relay at startup: "keep" (default), "off" or "on"
+	StartupState string `yaml:"startup_state"`
+
+	// Smart plug switching the printer, used by the smart plug drivers
+	SmartPlug SmartPlugConfig `yaml:"smart_plug"`
+}
+
+// SmartPlugConfig holds the HTTP settings of a smart plug
+type SmartPlugConfig struct {
+	// Base URL of the plug, e.g. http://192.168.1.50
+	URL string `yaml:"url"`
+
+	// Credentials of the plug's web interface, empty if it has none
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	// Relay of plugs with several outputs, counted from 0
+	Channel int `yaml:"channel"`
+
+	// Timeout of each request to the plug (default: 5)
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+}
+
+// Returns the timeout of requests to the smart plug
+func (s *SmartPlugConfig) GetTimeout() time.Duration {
+	if s.TimeoutSeconds <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(s.TimeoutSeconds) * time.Second
+}
+
+// Returns true if the driver switches a smart plug over HTTP
+func (g *GPIOConfig) IsSmartPlug() bool {
+	switch g.Driver {
+	case GPIODriverTasmota, GPIODriverShelly, GPIODriverShellyRPC:
+		return true
+	}
+	return false
+}
+
+// Returns a short description of the switch for logs, e.g. "GPIO17" or "tasmota plug at http://..."
+func (g *GPIOConfig) String() string {
+	if g.IsSmartPlug() {
+		return fmt.Sprintf("%s plug at %s", g.Driver, g.SmartPlug.URL)
+	}
+	if g.Driver == GPIODriverChardev {
+		return fmt.Sprintf("%s line %d", g.GetChip(), g.RelayPin)
+	}
+	return fmt.Sprintf("GPIO%d", g.RelayPin)
+}
+
+// Relay polarities
+const (
+	GPIOPolarityActiveLow  = "active_low"
+	GPIOPolarityActiveHigh = "active_high"
+)
+
+// Relay startup policies
+const (
+	GPIOStartupKeep = "keep"
+	GPIOStartupOff  = "off"
+	GPIOStartupOn   = "on"
+)
+
+// Chip used by the chardev driver when none is configured
+const defaultGPIOChip = "gpiochip0"
+
+// Returns the GPIO chip of the chardev driver
+func (g *GPIOConfig) GetChip() string {
+	if g.Chip == "" {
+		return defaultGPIOChip
+	}
+	return g.Chip
+}
+
+// Returns true if a low level switches the relay on
+func (g *GPIOConfig) IsActiveLow() bool {
+	return g.Polarity != GPIOPolarityActiveHigh
+}
+
+// Returns the startup policy of the relay
+func (g *GPIOConfig) GetStartupState() string {
+	if g.StartupState == "" {
+		return GPIOStartupKeep
+	}
+	return g.StartupState
+}
+
+// Checks the driver, polarity and startup policy
+func (g *GPIOConfig) Validate() error {
+	switch g.Driver {
+	case "", GPIODriverPeriph, GPIODriverChardev, GPIODriverSimulated:
+	case GPIODriverTasmota, GPIODriverShelly, GPIODriverShellyRPC:
+		if g.SmartPlug.URL == "" {
+			return fmt.Errorf("driver '%s' needs the url of the smart plug", g.Driver)
+		}
+		if g.SmartPlug.Channel < 0 {
+			return fmt.Errorf("smart plug channel must not be negative")
+		}
+		// Gen2 plugs use digest authentication, which is not supported
+		if g.Driver == GPIODriverShellyRPC && g.SmartPlug.Username != "" {
+			return fmt.Errorf("driver '%s' only supports plugs without authentication", g.Driver)
+		}
+	default:
+		return fmt.Errorf("unknown driver '%s'", g.Driver)
+	}
+
+	switch g.Polarity {
+	case "", GPIOPolarityActiveLow, GPIOPolarityActiveHigh:
+	default:
+		return fmt.Errorf("unknown polarity '%s', use %s or %s", g.Polarity, GPIOPolarityActiveLow, GPIOPolarityActiveHigh)
+	}
+
+	switch g.StartupState {
+	case "", GPIOStartupKeep, GPIOStartupOff, GPIOStartupOn:
+	default:
+		return fmt.Errorf("unknown startup state '%s', use %s, %s or %s", g.StartupState, GPIOStartupKeep, GPIOStartupOff, GPIOStartupOn)
+	}
+	return nil
 }
 
 // LoggingConfig holds logging-specific configuration
@@ -64,6 +410,107 @@ type LoggingConfig struct {
 	Level string `yaml:"level"`
 }
 
+// MQTTConfig holds the MQTT and Home Assistant settings
+// Broker credentials are read from the MQTT_USERNAME and MQTT_PASSWORD environment variables
+type MQTTConfig struct {
+	// Connect to the broker and publish the printers
+	Enabled bool `yaml:"enabled"`
+
+	// Broker URL, e.g. tcp://homeassistant.local:1883
+	Broker string `yaml:"broker"`
+
+	// Client ID, also identifies the devices in Home Assistant (default: brother-cube-telegram)
+	ClientID string `yaml:"client_id"`
+
+	// Prefix of all state and command topics (default: brother-cube)
+	TopicPrefix string `yaml:"topic_prefix"`
+
+	// Prefix Home Assistant watches for discovery messages (default: homeassistant)
+	DiscoveryPrefix string `yaml:"discovery_prefix"`
+
+	// Interval between state updates besides the ones after jobs (default: 60)
+	PublishIntervalSeconds int `yaml:"publish_interval_seconds"`
+}
+
+// Returns the MQTT client ID
+func (m *MQTTConfig) GetClientID() string {
+	if m.ClientID == "" {
+		return "brother-cube-telegram"
+	}
+	return m.ClientID
+}
+
+// Returns the prefix of the state and command topics
+func (m *MQTTConfig) GetTopicPrefix() string {
+	if m.TopicPrefix == "" {
+		return "brother-cube"
+	}
+	return strings.TrimSuffix(m.TopicPrefix, "/")
+}
+
+// Returns the Home Assistant discovery prefix
+func (m *MQTTConfig) GetDiscoveryPrefix() string {
+	if m.DiscoveryPrefix == "" {
+		return "homeassistant"
+	}
+	return strings.TrimSuffix(m.DiscoveryPrefix, "/")
+}
+
+// Returns the interval between periodic state updates
+func (m *MQTTConfig) GetPublishInterval() time.Duration {
+	if m.PublishIntervalSeconds <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(m.PublishIntervalSeconds) * time.Second
+}
+
+// APIConfig holds the settings of the local HTTP API
+// Clients authenticate with the token from the API_TOKEN environment variable
+type APIConfig struct {
+	// Start the HTTP server
+	Enabled bool `yaml:"enabled"`
+
+	// Address the server listens on (default: :8080)
+	Listen string `yaml:"listen"`
+
+	// Serve the web dashboard on / of the same address
+	Dashboard bool `yaml:"dashboard"`
+}
+
+// Returns the address the HTTP server listens on
+func (a *APIConfig) GetListen() string {
+	if a.Listen == "" {
+		return ":8080"
+	}
+	return a.Listen
+}
+
+// MetricsConfig holds the settings of the Prometheus metrics endpoint
+type MetricsConfig struct {
+	// Serve /metrics
+	Enabled bool `yaml:"enabled"`
+
+	// Address the metrics server listens on (default: :2112)
+	Listen string `yaml:"listen"`
+}
+
+// Returns the address the metrics server listens on
+func (m *MetricsConfig) GetListen() string {
+	if m.Listen == "" {
+		return ":2112"
+	}
+	return m.Listen
+}
+
+// HistoryConfig holds print history configuration
+type HistoryConfig struct {
+	// Path to the history database file
+	Path string `yaml:"path"`
+
+	// Number of entries shown by /history when no count is given
+	DefaultEntries int `yaml:"default_entries"`
+}
+
 // Global config instance
 var cfg *Config
 
@@ -81,7 +528,109 @@ func Load(configPath string) error {
 
 	// Process the drafts folder path (expand ~ to home directory)
 	cfg.Printer.DraftsFolder = expandPath(cfg.Printer.DraftsFolder)
+	cfg.Printer.FileBackend.OutputFolder = expandPath(cfg.Printer.FileBackend.OutputFolder)
+	cfg.History.Path = expandPath(cfg.History.Path)
+
+	if err := loadPrinters(data); err != nil {
+		return err
+	}
 
+	if cfg.MQTT.Enabled && cfg.MQTT.Broker == "" {
+		return fmt.Errorf("mqtt is enabled but no broker is configured")
+	}
+
+	for name, preset := range cfg.Printer.Presets {
+		if preset.Printer != "" && cfg.GetPrinter(preset.Printer) == nil {
+			return fmt.Errorf("preset '%s' uses unknown printer '%s'", name, preset.Printer)
+		}
+		if _, ok := ParseBarcodeType(preset.Barcode); preset.Barcode != "" && !ok {
+			return fmt.Errorf("preset '%s' has unsupported barcode type '%s', expected one of %s", name, preset.Barcode, strings.Join(BarcodeTypes(), ", "))
+		}
+		if preset.Layout != nil {
+			if err := preset.Layout.Validate(); err != nil {
+				return fmt.Errorf("preset '%s' has an invalid layout: %v", name, err)
+			}
+		}
+		if preset.Template == "" {
+			continue
+		}
+		if err := templates.Validate(name, preset.Template, cfg.Printer.DateFormat); err != nil {
+			return fmt.Errorf("preset '%s' has an invalid template: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Fills cfg.Printers from the printers list, each starting from the printer section
+func loadPrinters(data []byte) error {
+	var raw struct {
+		Printers []yaml.Node `yaml:"printers"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse printers: %v", err)
+	}
+
+	if len(raw.Printers) == 0 {
+		gpio := cfg.GPIO
+		cfg.Printers = []NamedPrinterConfig{{Name: DefaultPrinterName, GPIO: &gpio, PrinterConfig: cfg.Printer}}
+	}
+
+	for _, node := range raw.Printers {
+		// Decoding into a copy of the printer section keeps every setting the entry does not override
+		printer := NamedPrinterConfig{PrinterConfig: cfg.Printer}
+		printer.Presets = nil
+		if err := node.Decode(&printer); err != nil {
+			return fmt.Errorf("failed to parse printer: %v", err)
+		}
+
+		if printer.Name == "" {
+			return fmt.Errorf("printer on line %d has no name", node.Line)
+		}
+		if cfg.GetPrinter(printer.Name) != nil {
+			return fmt.Errorf("printer '%s' is configured twice", printer.Name)
+		}
+		if printer.Presets != nil {
+			return fmt.Errorf("printer '%s' defines presets, presets belong in the printer section and can be routed with their printer setting", printer.Name)
+		}
+
+		printer.Presets = cfg.Printer.Presets
+		printer.DraftsFolder = expandPath(printer.DraftsFolder)
+		printer.FileBackend.OutputFolder = expandPath(printer.FileBackend.OutputFolder)
+		cfg.Printers = append(cfg.Printers, printer)
+	}
+
+	for _, printer := range cfg.Printers {
+		if err := printer.Layout.Validate(); err != nil {
+			return fmt.Errorf("printer '%s' has an invalid layout: %v", printer.Name, err)
+		}
+		if printer.GPIO == nil {
+			continue
+		}
+		if err := printer.GPIO.Validate(); err != nil {
+			return fmt.Errorf("printer '%s' has an invalid gpio section: %v", printer.Name, err)
+		}
+	}
+
+	if cfg.Routing.Default != "" && cfg.GetPrinter(cfg.Routing.Default) == nil {
+		return fmt.Errorf("routing uses unknown default printer '%s'", cfg.Routing.Default)
+	}
+	for chatID, name := range cfg.Routing.Chats {
+		if cfg.GetPrinter(name) == nil {
+			return fmt.Errorf("routing for chat %d uses unknown printer '%s'", chatID, name)
+		}
+	}
+
+	return nil
+}
+
+// Returns a named printer, or nil if not found
+func (c *Config) GetPrinter(name string) *NamedPrinterConfig {
+	for i := range c.Printers {
+		if c.Printers[i].Name == name {
+			return &c.Printers[i]
+		}
+	}
 	return nil
 }
 
@@ -93,6 +642,14 @@ func Get() *Config {
 	return cfg
 }
 
+// Returns the name of the backend, ptouch if none is set
+func (p *PrinterConfig) GetBackend() string {
+	if p.Backend == "" {
+		return BackendPtouch
+	}
+	return p.Backend
+}
+
 // Returns the auto-shutdown delay as a time.Duration
 func (p *PrinterConfig) GetAutoShutdownDelay() time.Duration {
 	return time.Duration(p.AutoShutdownDelayMinutes) * time.Minute
@@ -103,6 +660,22 @@ func (p *PrinterConfig) GetRetryDelay(attemptNumber int) time.Duration {
 	return time.Duration(attemptNumber+p.RetryBaseDelaySeconds) * time.Second
 }
 
+// Timeout for ptouch-print invocations used when none is configured
+const defaultCommandTimeout = 30 * time.Second
+
+// Returns the deadline for a single ptouch-print invocation
+func (p *PrinterConfig) GetCommandTimeout() time.Duration {
+	if p.CommandTimeoutSeconds <= 0 {
+		return defaultCommandTimeout
+	}
+	return time.Duration(p.CommandTimeoutSeconds) * time.Second
+}
+
+// Returns the time between monitor polls, 0 if monitoring is disabled
+func (p *PrinterConfig) GetMonitorInterval() time.Duration {
+	return time.Duration(p.Monitor.IntervalSeconds) * time.Second
+}
+
 // Returns the folder permissions as os.FileMode
 func (p *PrinterConfig) GetFolderPermissions() os.FileMode {
 	return os.FileMode(p.FolderPermissions)
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Printers the preset fixtures can route to
+const testPrinters = `
+printers:
+  - name: office
+  - name: kitchen
+    layout:
+      chain: true
+`
+
+// Writes the YAML to a config file and loads it
+func loadTestConfig(t *testing.T, data string) error {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoadValidPreset(t *testing.T) {
+	err := loadTestConfig(t, testPrinters+`
+printer:
+  date_format: "2006-01-02"
+  presets:
+    jars:
+      font_size: auto
+      max_lines: 2
+      printer: kitchen
+      template: "{{.Text}} #{{.Counter}} opened {{today}}, use by {{addDays 7}}"
+      barcode: "EAN-13"
+      series:
+        count: 10
+      layout:
+        cut_marks: around
+        min_length_mm: 30
+`)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	preset := Get().Printer.GetPreset("jars")
+	if preset == nil {
+		t.Fatal("preset 'jars' not loaded")
+	}
+	if !preset.FontSize.IsAuto() || preset.Printer != "kitchen" || preset.Series.Count != 10 || preset.Layout.MinLengthMM != 30 {
+		t.Errorf("preset loaded as %+v", preset)
+	}
+	if printer := Get().GetPrinter("kitchen"); printer == nil || printer.GetPreset("jars") == nil {
+		t.Error("printers do not share the presets of the printer section")
+	}
+}
+
+func TestLoadInvalidPreset(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset string
+		err    string
+	}{
+		{
+			name:   "unknown printer",
+			preset: `printer: garage`,
+			err:    "preset 'broken' uses unknown printer 'garage'",
+		},
+		{
+			name:   "unparsable template",
+			preset: `template: "{{.Text"`,
+			err:    "preset 'broken' has an invalid template",
+		},
+		{
+			name:   "unknown template function",
+			preset: `template: "{{.Text}} {{tomorrow}}"`,
+			err:    "preset 'broken' has an invalid template",
+		},
+		{
+			name:   "unknown template field",
+			preset: `template: "{{.Owner}}"`,
+			err:    "preset 'broken' has an invalid template",
+		},
+		{
+			name:   "unsupported barcode",
+			preset: `barcode: qr`,
+			err:    "preset 'broken' has unsupported barcode type 'qr', expected one of code128, ean13, code39",
+		},
+		{
+			name: "unknown cut marks",
+			preset: `layout:
+        cut_marks: everywhere`,
+			err: "preset 'broken' has an invalid layout: unknown cut mark mode 'everywhere'",
+		},
+		{
+			name: "negative padding",
+			preset: `layout:
+        leading_mm: -2`,
+			err: "preset 'broken' has an invalid layout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadTestConfig(t, testPrinters+`
+printer:
+  presets:
+    broken:
+      `+tt.preset+`
+`)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidPrinterLayout(t *testing.T) {
+	err := loadTestConfig(t, `
+printers:
+  - name: office
+    layout:
+      cut_marks: sometimes
+`)
+	if err == nil || !strings.Contains(err.Error(), "printer 'office' has an invalid layout") {
+		t.Fatalf("expected a layout error, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package gpio
+
+import (
+	"brother-cube-telegram/logger"
+	"fmt"
+
+	"github.com/warthog618/go-gpiocdev"
+)
+
+// Logical values of the relay line, the kernel applies the polarity
+const (
+	chardevLineOn  = 1
+	chardevLineOff = 0
+)
+
+// Relay on a line of a Linux GPIO character device (/dev/gpiochipN)
+// Works on any board with a recent kernel, without the sysfs or memory-mapped access periph.io needs
+type ChardevRelay struct {
+	line   *gpiocdev.Line
+	chip   string
+	offset int
+	on     bool
+}
+
+// Requests the line at offset on the chip (e.g. "gpiochip0") as an output
+// activeLow selects whether a low or a high level switches the relay on
+// A line that is already an output keeps its level, which gives the initial state, otherwise the relay starts off
+func NewChardevRelay(chip string, offset int, activeLow bool) (*ChardevRelay, error) {
+	options := []gpiocdev.LineReqOption{gpiocdev.AsIs, gpiocdev.WithConsumer("brother-cube-telegram")}
+	if activeLow {
+		options = append(options, gpiocdev.AsActiveLow)
+	}
+
+	line, err := gpiocdev.RequestLine(chip, offset, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request line %d of %s: %v", offset, chip, err)
+	}
+
+	// Keep the level of a line that already drives the relay so a running printer is not switched off
+	// An input line reads a level nobody set, with active_low a low read would switch the printer on
+	info, err := line.Info()
+	if err != nil {
+		line.Close()
+		return nil, fmt.Errorf("failed to read line %d of %s: %v", offset, chip, err)
+	}
+	value := chardevLineOff
+	if info.Config.Direction == gpiocdev.LineDirectionOutput {
+		if value, err = line.Value(); err != nil {
+			line.Close()
+			return nil, fmt.Errorf("failed to read line %d of %s: %v", offset, chip, err)
+		}
+	} else {
+		logger.Info("%s line %d is not an output yet, starting with the relay OFF", chip, offset)
+	}
+	if err := line.Reconfigure(gpiocdev.AsOutput(value)); err != nil {
+		line.Close()
+		return nil, fmt.Errorf("failed to configure line %d of %s as output: %v", offset, chip, err)
+	}
+
+	relay := &ChardevRelay{
+		line:   line,
+		chip:   chip,
+		offset: offset,
+		on:     value == chardevLineOn,
+	}
+
+	logger.Info("%s line %d initialized as relay control (%s, currently %s)", chip, offset, polarityName(activeLow), stateName(relay.on))
+
+	return relay, nil
+}
+
+// Creates a chardev relay as a Switch
+func newChardevSwitch(chip string, offset int, activeLow bool) (Switch, error) {
+	relay, err := NewChardevRelay(chip, offset, activeLow)
+	if err != nil {
+		return nil, err
+	}
+	return relay, nil
+}
+
+// Activates the relay
+func (r *ChardevRelay) TurnOn() error {
+	if err := r.line.SetValue(chardevLineOn); err != nil {
+		return fmt.Errorf("failed to turn on relay on %s line %d: %v", r.chip, r.offset, err)
+	}
+	r.on = true
+	logger.Info("Relay on %s line %d turned ON", r.chip, r.offset)
+	return nil
+}
+
+// Deactivates the relay
+func (r *ChardevRelay) TurnOff() error {
+	if err := r.line.SetValue(chardevLineOff); err != nil {
+		return fmt.Errorf("failed to turn off relay on %s line %d: %v", r.chip, r.offset, err)
+	}
+	r.on = false
+	logger.Info("Relay on %s line %d turned OFF", r.chip, r.offset)
+	return nil
+}
+
+// Toggles the relay state
+func (r *ChardevRelay) Toggle() error {
+	if r.GetState() {
+		return r.TurnOff()
+	}
+	return r.TurnOn()
+}
+
+// Returns the current state of the relay, read back from the line
+// Falls back to the last switched state if the line cannot be read
+func (r *ChardevRelay) GetState() bool {
+	value, err := r.line.Value()
+	if err != nil {
+		logger.Warn("Failed to read relay on %s line %d: %v", r.chip, r.offset, err)
+		return r.on
+	}
+	return value == chardevLineOn
+}
+
+// Turns the relay off and releases the line
+func (r *ChardevRelay) Close() error {
+	if err := r.TurnOff(); err != nil {
+		logger.Warn("Failed to turn off relay during close: %v", err)
+	}
+
+	logger.Info("%s line %d relay closed", r.chip, r.offset)
+	return r.line.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package gpio
+
+import "fmt"
+
+// Always fails, GPIO character devices only exist on Linux
+func newChardevSwitch(chip string, offset int, activeLow bool) (Switch, error) {
+	return nil, fmt.Errorf("GPIO character devices are only supported on Linux")
+}
@@ -0,0 +1,121 @@
+package gpio
+
+import (
+	"brother-cube-telegram/logger"
+	"fmt"
+
+	"periph.io/x/conn/v3/gpio"
+	"periph.io/x/conn/v3/gpio/gpioreg"
+	periphpin "periph.io/x/conn/v3/pin"
+	"periph.io/x/host/v3"
+)
+
+const (
+	turnOffAction = "Turn OFF"
+	turnOnAction  = "Turn ON"
+)
+
+// Relay on a Raspberry Pi GPIO pin driven through periph.io
+type PeriphRelay struct {
+	pin       gpio.PinIO
+	pinNum    int
+	activeLow bool
+}
+
+// Creates a new relay controller for the specified GPIO pin
+// activeLow selects whether a low or a high level switches the relay on
+// A pin that is already an output keeps its level, which gives the initial state, otherwise the relay starts off
+func NewPeriphRelay(pinNumber int, activeLow bool) (*PeriphRelay, error) {
+	// Initialize periph.io host
+	if _, err := host.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize host: %v", err)
+	}
+
+	// Get the GPIO pin
+	pin := gpioreg.ByName(fmt.Sprintf("GPIO%d", pinNumber))
+	if pin == nil {
+		return nil, fmt.Errorf("failed to find GPIO%d", pinNumber)
+	}
+
+	// Keep the level of a pin that already drives the relay so a running printer is not switched off
+	// An input pin reads a level nobody set, with active_low a low read would switch the printer on
+	level := gpio.Level(activeLow)
+	if isOutput(pin) {
+		level = pin.Read()
+	} else {
+		logger.Info("GPIO%d is not an output yet, starting with the relay OFF", pinNumber)
+	}
+	if err := pin.Out(level); err != nil {
+		return nil, fmt.Errorf("failed to configure GPIO%d as output: %v", pinNumber, err)
+	}
+
+	relay := &PeriphRelay{
+		pin:       pin,
+		pinNum:    pinNumber,
+		activeLow: activeLow,
+	}
+
+	logger.Info("GPIO%d initialized as relay control (%s, currently %s)", pinNumber, polarityName(activeLow), stateName(relay.GetState()))
+
+	return relay, nil
+}
+
+// Activates the relay
+func (r *PeriphRelay) TurnOn() error {
+	if err := r.pin.Out(r.level(true)); err != nil {
+		return fmt.Errorf("failed to turn on relay on GPIO%d: %v", r.pinNum, err)
+	}
+	logger.Info("Relay on GPIO%d turned ON", r.pinNum)
+	return nil
+}
+
+// Deactivates the relay
+func (r *PeriphRelay) TurnOff() error {
+	if err := r.pin.Out(r.level(false)); err != nil {
+		return fmt.Errorf("failed to turn off relay on GPIO%d: %v", r.pinNum, err)
+	}
+	logger.Info("Relay on GPIO%d turned OFF", r.pinNum)
+	return nil
+}
+
+// Toggles the relay state
+func (r *PeriphRelay) Toggle() error {
+	if r.GetState() {
+		return r.TurnOff()
+	}
+	return r.TurnOn()
+}
+
+// Returns the current state of the relay, read back from the pin level
+func (r *PeriphRelay) GetState() bool {
+	return r.pin.Read() == r.level(true)
+}
+
+// Cleans up the GPIO pin
+func (r *PeriphRelay) Close() error {
+	// Turn off relay before closing
+	if err := r.TurnOff(); err != nil {
+		logger.Warn("Failed to turn off relay during close: %v", err)
+	}
+
+	logger.Info("GPIO%d relay closed", r.pinNum)
+	return nil
+}
+
+// Returns true if the pin is configured as an output
+func isOutput(pin gpio.PinIO) bool {
+	withFunc, ok := pin.(periphpin.PinFunc)
+	if !ok {
+		return false
+	}
+	switch withFunc.Func() {
+	case gpio.OUT, gpio.OUT_HIGH, gpio.OUT_LOW:
+		return true
+	}
+	return false
+}
+
+// Returns the pin level for the relay state
+func (r *PeriphRelay) level(on bool) gpio.Level {
+	return gpio.Level(on != r.activeLow)
+}
@@ -0,0 +1,67 @@
+package gpio
+
+import (
+	"brother-cube-telegram/logger"
+	"sync"
+)
+
+// In-memory relay that only logs what a real one would do
+// Useful for development machines and tests without GPIO hardware
+type SimulatedRelay struct {
+	pinNum    int
+	activeLow bool
+
+	mu sync.Mutex
+	// Simulated pin level, true for high
+	high bool
+}
+
+// Creates a simulated relay, the pin number only appears in logs
+// The simulated pin starts at the level that switches the relay off
+func NewSimulatedRelay(pinNumber int, activeLow bool) *SimulatedRelay {
+	logger.Info("Simulated relay for GPIO%d initialized (%s)", pinNumber, polarityName(activeLow))
+	return &SimulatedRelay{pinNum: pinNumber, activeLow: activeLow, high: activeLow}
+}
+
+// Switches the simulated relay on
+func (r *SimulatedRelay) TurnOn() error {
+	r.set(true)
+	logger.Info("Simulated relay on GPIO%d turned ON", r.pinNum)
+	return nil
+}
+
+// Switches the simulated relay off
+func (r *SimulatedRelay) TurnOff() error {
+	r.set(false)
+	logger.Info("Simulated relay on GPIO%d turned OFF", r.pinNum)
+	return nil
+}
+
+// Toggles the relay state
+func (r *SimulatedRelay) Toggle() error {
+	if r.GetState() {
+		return r.TurnOff()
+	}
+	return r.TurnOn()
+}
+
+// Returns the current state of the relay, derived from the simulated pin level
+func (r *SimulatedRelay) GetState() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.high != r.activeLow
+}
+
+// Turns the simulated relay off
+func (r *SimulatedRelay) Close() error {
+	r.TurnOff()
+	logger.Info("Simulated relay on GPIO%d closed", r.pinNum)
+	return nil
+}
+
+// Sets the simulated pin level for the relay state
+func (r *SimulatedRelay) set(on bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.high = on != r.activeLow
+}
@@ -0,0 +1,282 @@
+package gpio
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Time a state read from the plug is used before it is read again
+// GetState is called while the power manager holds its lock, it must not wait for the plug
+const plugStateTTL = 5 * time.Second
+
+// Smart plug switched over its local HTTP API
+// Supports Tasmota, Shelly Gen1 (/relay) and Shelly Gen2 and later (/rpc)
+type SmartPlug struct {
+	driver  string
+	baseURL string
+	config  *config.SmartPlugConfig
+	client  *http.Client
+
+	// Last state reported by the plug, returned by GetState
+	mu         sync.Mutex
+	on         bool
+	checked    time.Time
+	generation int
+	refreshing bool
+}
+
+// Tasmota answers commands with the power state, e.g. {"POWER":"ON"} or {"POWER2":"OFF"}
+type tasmotaResponse map[string]any
+
+// Shelly Gen1 answers /relay/N with the relay state
+type shellyRelayResponse struct {
+	IsOn *bool `json:"ison"`
+}
+
+// Shelly Gen2 answers Switch.GetStatus with the output state
+type shellySwitchStatus struct {
+	Output *bool `json:"output"`
+}
+
+// Creates a smart plug for the driver ("tasmota", "shelly" or "shelly_rpc") and reads its state
+// An unreachable plug is reported but not fatal, it is switched once it answers again
+func NewSmartPlug(driver string, cfg *config.SmartPlugConfig) (*SmartPlug, error) {
+	base, err := url.Parse(cfg.URL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid smart plug url '%s'", cfg.URL)
+	}
+
+	plug := &SmartPlug{
+		driver:  driver,
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+		config:  cfg,
+		client:  &http.Client{Timeout: cfg.GetTimeout()},
+	}
+
+	on, err := plug.status()
+	if err != nil {
+		logger.Warn("Smart plug at %s is not reachable, assuming it is OFF: %v", plug.baseURL, err)
+	}
+	plug.remember(on)
+
+	logger.Info("%s plug at %s initialized as relay control (currently %s)", driver, plug.baseURL, stateName(on))
+
+	return plug, nil
+}
+
+// Switches the plug on
+func (p *SmartPlug) TurnOn() error {
+	if err := p.set(true); err != nil {
+		return fmt.Errorf("failed to turn on smart plug at %s: %v", p.baseURL, err)
+	}
+	logger.Info("Smart plug at %s turned ON", p.baseURL)
+	return nil
+}
+
+// Switches the plug off
+func (p *SmartPlug) TurnOff() error {
+	if err := p.set(false); err != nil {
+		return fmt.Errorf("failed to turn off smart plug at %s: %v", p.baseURL, err)
+	}
+	logger.Info("Smart plug at %s turned OFF", p.baseURL)
+	return nil
+}
+
+// Toggles the plug state
+func (p *SmartPlug) Toggle() error {
+	if p.GetState() {
+		return p.TurnOff()
+	}
+	return p.TurnOn()
+}
+
+// Returns the last state reported by the plug without waiting for it
+// A state older than plugStateTTL is read again in the background
+func (p *SmartPlug) GetState() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.refreshing && time.Since(p.checked) >= plugStateTTL {
+		p.refreshing = true
+		go p.refresh(p.generation)
+	}
+	return p.on
+}
+
+// Turns the plug off
+func (p *SmartPlug) Close() error {
+	if err := p.TurnOff(); err != nil {
+		logger.Warn("Failed to turn off relay during close: %v", err)
+	}
+
+	logger.Info("Smart plug at %s closed", p.baseURL)
+	return nil
+}
+
+// Stores the state last reported by the plug
+func (p *SmartPlug) remember(on bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.on = on
+	p.checked = time.Now()
+	p.generation++
+}
+
+// Reads the state of the plug for GetState
+// The result is dropped if the plug was switched meanwhile, it may predate the switch
+// The last known state is kept if the plug cannot be reached
+func (p *SmartPlug) refresh(generation int) {
+	on, err := p.status()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refreshing = false
+	if err != nil {
+		logger.Warn("Failed to read smart plug at %s: %v", p.baseURL, err)
+		// Retried after the TTL, not on every call while the plug is unreachable
+		p.checked = time.Now()
+		return
+	}
+	if generation == p.generation {
+		p.on = on
+		p.checked = time.Now()
+	}
+}
+
+// Switches the plug and checks the state it reports back
+func (p *SmartPlug) set(on bool) error {
+	var state bool
+	var err error
+
+	switch p.driver {
+	case config.GPIODriverTasmota:
+		action := "Off"
+		if on {
+			action = "On"
+		}
+		state, err = p.tasmota(action)
+	case config.GPIODriverShelly:
+		action := "off"
+		if on {
+			action = "on"
+		}
+		state, err = p.shellyRelay(url.Values{"turn": {action}})
+	case config.GPIODriverShellyRPC:
+		query := url.Values{"id": {fmt.Sprint(p.config.Channel)}, "on": {fmt.Sprint(on)}}
+		// Switch.Set only returns the previous state, read the new one back
+		if err = p.get("/rpc/Switch.Set", query, &map[string]any{}); err == nil {
+			state, err = p.status()
+		}
+	default:
+		err = fmt.Errorf("unknown smart plug driver '%s'", p.driver)
+	}
+
+	if err != nil {
+		return err
+	}
+	p.remember(state)
+	if state != on {
+		return fmt.Errorf("plug reports %s", stateName(state))
+	}
+	return nil
+}
+
+// Asks the plug for its current state
+func (p *SmartPlug) status() (bool, error) {
+	switch p.driver {
+	case config.GPIODriverTasmota:
+		return p.tasmota("")
+	case config.GPIODriverShelly:
+		return p.shellyRelay(nil)
+	case config.GPIODriverShellyRPC:
+		var status shellySwitchStatus
+		if err := p.get("/rpc/Switch.GetStatus", url.Values{"id": {fmt.Sprint(p.config.Channel)}}, &status); err != nil {
+			return false, err
+		}
+		if status.Output == nil {
+			return false, fmt.Errorf("switch %d has no output state", p.config.Channel)
+		}
+		return *status.Output, nil
+	default:
+		return false, fmt.Errorf("unknown smart plug driver '%s'", p.driver)
+	}
+}
+
+// Runs a Tasmota power command, an empty action only reads the state
+func (p *SmartPlug) tasmota(action string) (bool, error) {
+	command := fmt.Sprintf("Power%d", p.config.Channel+1)
+	query := url.Values{"cmnd": {strings.TrimSpace(command + " " + action)}}
+	if p.config.Username != "" {
+		query.Set("user", p.config.Username)
+		query.Set("password", p.config.Password)
+	}
+
+	var response tasmotaResponse
+	if err := p.get("/cm", query, &response); err != nil {
+		return false, err
+	}
+
+	// Plugs with a single relay answer with POWER instead of POWER1
+	for _, key := range []string{strings.ToUpper(command), "POWER"} {
+		if value, ok := response[key].(string); ok {
+			return strings.EqualFold(value, "ON"), nil
+		}
+	}
+	return false, fmt.Errorf("unexpected answer to %s", command)
+}
+
+// Reads or switches the relay of a Shelly Gen1 plug
+func (p *SmartPlug) shellyRelay(query url.Values) (bool, error) {
+	var response shellyRelayResponse
+	if err := p.get(fmt.Sprintf("/relay/%d", p.config.Channel), query, &response); err != nil {
+		return false, err
+	}
+	if response.IsOn == nil {
+		return false, fmt.Errorf("relay %d has no state", p.config.Channel)
+	}
+	return *response.IsOn, nil
+}
+
+// Sends a GET request to the plug and decodes the JSON answer into result
+func (p *SmartPlug) get(path string, query url.Values, result any) error {
+	target := p.baseURL + path
+	if len(query) > 0 {
+		// Tasmota expects spaces in commands as %20
+		target += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+
+	request, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	if p.driver != config.GPIODriverTasmota && p.config.Username != "" {
+		request.SetBasicAuth(p.config.Username, p.config.Password)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		// Drop the URL from the error, it can contain the Tasmota password
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("plug answered %s", response.Status)
+	}
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode answer of the plug: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package gpio
+
+import (
+	"brother-cube-telegram/config"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Plug stand-in answering like the firmware of a driver
+type fakePlug struct {
+	driver string
+
+	mu sync.Mutex
+	on bool
+	// Ignores switch commands, like a plug with a locked relay
+	stuck bool
+	// Delays every answer
+	delay time.Duration
+}
+
+func (f *fakePlug) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(f.delay)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	switch {
+	case f.driver == config.GPIODriverTasmota && r.URL.Path == "/cm":
+		_, action, _ := strings.Cut(query.Get("cmnd"), " ")
+		if action != "" {
+			f.switchTo(strings.EqualFold(action, "on"))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"POWER": onOff(f.on)})
+	case f.driver == config.GPIODriverShelly && r.URL.Path == "/relay/0":
+		if turn := query.Get("turn"); turn != "" {
+			f.switchTo(turn == "on")
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"ison": f.on})
+	case f.driver == config.GPIODriverShellyRPC && r.URL.Path == "/rpc/Switch.Set":
+		wasOn := f.on
+		f.switchTo(query.Get("on") == "true")
+		json.NewEncoder(w).Encode(map[string]bool{"was_on": wasOn})
+	case f.driver == config.GPIODriverShellyRPC && r.URL.Path == "/rpc/Switch.GetStatus":
+		json.NewEncoder(w).Encode(map[string]any{"id": 0, "output": f.on})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakePlug) switchTo(on bool) {
+	if !f.stuck {
+		f.on = on
+	}
+}
+
+func (f *fakePlug) state() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.on
+}
+
+func (f *fakePlug) set(on bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.on = on
+}
+
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+var plugDrivers = []string{config.GPIODriverTasmota, config.GPIODriverShelly, config.GPIODriverShellyRPC}
+
+// Starts a fake plug and connects a SmartPlug to it
+func newTestPlug(t *testing.T, fake *fakePlug) (*SmartPlug, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	plug, err := NewSmartPlug(fake.driver, &config.SmartPlugConfig{URL: server.URL, TimeoutSeconds: 1})
+	if err != nil {
+		t.Fatalf("NewSmartPlug: %v", err)
+	}
+	return plug, server
+}
+
+func TestSmartPlugSwitch(t *testing.T) {
+	for _, driver := range plugDrivers {
+		t.Run(driver, func(t *testing.T) {
+			fake := &fakePlug{driver: driver}
+			plug, _ := newTestPlug(t, fake)
+
+			if err := plug.TurnOn(); err != nil {
+				t.Fatalf("TurnOn: %v", err)
+			}
+			if !fake.state() || !plug.GetState() {
+				t.Fatalf("plug is %v, GetState %v after TurnOn", fake.state(), plug.GetState())
+			}
+
+			if err := plug.Toggle(); err != nil {
+				t.Fatalf("Toggle: %v", err)
+			}
+			if fake.state() || plug.GetState() {
+				t.Fatalf("plug is %v, GetState %v after Toggle", fake.state(), plug.GetState())
+			}
+		})
+	}
+}
+
+func TestSmartPlugReadsInitialState(t *testing.T) {
+	for _, driver := range plugDrivers {
+		t.Run(driver, func(t *testing.T) {
+			plug, _ := newTestPlug(t, &fakePlug{driver: driver, on: true})
+			if !plug.GetState() {
+				t.Fatal("GetState is false for a plug that is on")
+			}
+		})
+	}
+}
+
+func TestSmartPlugRefreshesState(t *testing.T) {
+	for _, driver := range plugDrivers {
+		t.Run(driver, func(t *testing.T) {
+			fake := &fakePlug{driver: driver}
+			plug, _ := newTestPlug(t, fake)
+
+			// Switched with the button of the plug, noticed once the state is stale
+			fake.set(true)
+			if plug.GetState() {
+				t.Fatal("GetState read the plug again before the state was stale")
+			}
+			plug.mu.Lock()
+			plug.checked = time.Time{}
+			plug.mu.Unlock()
+
+			waitFor(t, plug.GetState)
+		})
+	}
+}
+
+func TestSmartPlugMismatchedReadback(t *testing.T) {
+	for _, driver := range plugDrivers {
+		t.Run(driver, func(t *testing.T) {
+			fake := &fakePlug{driver: driver, stuck: true}
+			plug, _ := newTestPlug(t, fake)
+
+			err := plug.TurnOn()
+			if err == nil || !strings.Contains(err.Error(), "plug reports OFF") {
+				t.Fatalf("TurnOn of a stuck plug returned %v", err)
+			}
+			if plug.GetState() {
+				t.Fatal("GetState is true although the plug reported OFF")
+			}
+		})
+	}
+}
+
+func TestSmartPlugUnreachable(t *testing.T) {
+	for _, driver := range plugDrivers {
+		t.Run(driver, func(t *testing.T) {
+			fake := &fakePlug{driver: driver, on: true}
+			plug, server := newTestPlug(t, fake)
+			server.Close()
+
+			if err := plug.TurnOff(); err == nil {
+				t.Fatal("TurnOff of an unreachable plug succeeded")
+			}
+
+			plug.mu.Lock()
+			plug.checked = time.Time{}
+			plug.mu.Unlock()
+			if !plug.GetState() {
+				t.Fatal("GetState did not keep the last known state")
+			}
+			waitForRefresh(t, plug)
+			if !plug.GetState() {
+				t.Fatal("failed refresh replaced the last known state")
+			}
+		})
+	}
+}
+
+func TestSmartPlugGetStateDoesNotWait(t *testing.T) {
+	for _, driver := range plugDrivers {
+		t.Run(driver, func(t *testing.T) {
+			fake := &fakePlug{driver: driver}
+			plug, _ := newTestPlug(t, fake)
+
+			fake.mu.Lock()
+			fake.delay = 500 * time.Millisecond
+			fake.mu.Unlock()
+			plug.mu.Lock()
+			plug.checked = time.Time{}
+			plug.mu.Unlock()
+
+			start := time.Now()
+			for range 10 {
+				plug.GetState()
+			}
+			if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+				t.Fatalf("GetState waited %v for a slow plug", elapsed)
+			}
+			waitForRefresh(t, plug)
+		})
+	}
+}
+
+func TestNewSmartPlugInvalidURL(t *testing.T) {
+	for _, url := range []string{"", "192.168.1.50", "http://"} {
+		t.Run(fmt.Sprintf("%q", url), func(t *testing.T) {
+			if _, err := NewSmartPlug(config.GPIODriverTasmota, &config.SmartPlugConfig{URL: url}); err == nil {
+				t.Fatal("invalid url was accepted")
+			}
+		})
+	}
+}
+
+// Waits until the condition is true
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Waits until the background read of the plug state is finished
+func waitForRefresh(t *testing.T, plug *SmartPlug) {
+	t.Helper()
+	waitFor(t, func() bool {
+		plug.mu.Lock()
+		defer plug.mu.Unlock()
+		return !plug.refreshing
+	})
+}
@@ -0,0 +1,85 @@
+package gpio
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/logger"
+	"fmt"
+)
+
+// Switches the power of a printer on and off
+type Switch interface {
+	// Powers the printer
+	TurnOn() error
+	// Cuts the power of the printer
+	TurnOff() error
+	// Switches to the opposite state
+	Toggle() error
+	// Returns true if the printer is powered
+	GetState() bool
+	// Turns the power off and releases the switch
+	Close() error
+}
+
+// Creates the relay selected by the driver in the GPIO configuration
+// The relay state is read from the pin, then the startup policy is applied
+func NewSwitch(cfg *config.GPIOConfig) (Switch, error) {
+	relay, err := newDriverSwitch(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.GetStartupState() {
+	case config.GPIOStartupOff:
+		err = relay.TurnOff()
+	case config.GPIOStartupOn:
+		err = relay.TurnOn()
+	default:
+		logger.Info("Leaving relay %s at startup", stateName(relay.GetState()))
+	}
+	if err != nil {
+		relay.Close()
+		return nil, fmt.Errorf("failed to apply startup state '%s': %v", cfg.GetStartupState(), err)
+	}
+	return relay, nil
+}
+
+// Creates the relay of the configured driver
+func newDriverSwitch(cfg *config.GPIOConfig) (Switch, error) {
+	// Return the error explicitly, a nil relay pointer would make a non-nil Switch
+	switch cfg.Driver {
+	case "", config.GPIODriverPeriph:
+		relay, err := NewPeriphRelay(cfg.RelayPin, cfg.IsActiveLow())
+		if err != nil {
+			return nil, err
+		}
+		return relay, nil
+	case config.GPIODriverChardev:
+		return newChardevSwitch(cfg.GetChip(), cfg.RelayPin, cfg.IsActiveLow())
+	case config.GPIODriverSimulated:
+		return NewSimulatedRelay(cfg.RelayPin, cfg.IsActiveLow()), nil
+	case config.GPIODriverTasmota, config.GPIODriverShelly, config.GPIODriverShellyRPC:
+		plug, err := NewSmartPlug(cfg.Driver, &cfg.SmartPlug)
+		if err != nil {
+			return nil, err
+		}
+		return plug, nil
+	default:
+		return nil, fmt.Errorf("unknown GPIO driver '%s'", cfg.Driver)
+	}
+}
+
+// Returns "ON" or "OFF" for logs
+func stateName(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// Returns the polarity for logs
+func polarityName(activeLow bool) string {
+	if activeLow {
+		return config.GPIOPolarityActiveLow
+	}
+	return config.GPIOPolarityActiveHigh
+}
@@ -0,0 +1,337 @@
+package history
+
+import (
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket holding one entry per printed label, keyed by sequence number
+var printsBucket = []byte("prints")
+
+// Bucket holding the template counters, keyed by preset name
+var countersBucket = []byte("counters")
+
+// Outcome of a recorded print
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailed  = "failed"
+)
+
+// Largest image kept with an entry, larger images are dropped and the entry cannot be reprinted
+const MaxImageBytes = 1 << 20
+
+// A single recorded print
+type Entry struct {
+	ID        uint64         `json:"id"`
+	UserID    int64          `json:"user_id"`
+	ChatID    int64          `json:"chat_id"`
+	Username  string         `json:"username"`
+	Preset    string         `json:"preset,omitempty"`
+	Printer   string         `json:"printer,omitempty"`
+	Label     printers.Label `json:"label"`
+	Timestamp time.Time      `json:"timestamp"`
+	Outcome   string         `json:"outcome"`
+	Error     string         `json:"error,omitempty"`
+	// Set when the image of the label was larger than MaxImageBytes and not stored
+	ImageDropped bool `json:"image_dropped,omitempty"`
+}
+
+// Returns the label text on a single line, marking labels whose image was dropped
+func (e *Entry) DisplayText() string {
+	if e.ImageDropped {
+		return strings.TrimSpace("[image] " + e.Label.DisplayText())
+	}
+	return e.Label.DisplayText()
+}
+
+// Reports whether the stored label is complete and can be printed again
+func (e *Entry) Reprintable() bool {
+	return !e.ImageDropped
+}
+
+// Persists print history in a local bbolt database
+type Store struct {
+	db *bolt.DB
+
+	// Counter values of queued labels by counter name, see Reservation
+	reserved     map[string]map[uint64]bool
+	counterMutex sync.Mutex
+}
+
+// Opens (or creates) the history database at the given path
+func Open(path string, folderPermissions os.FileMode) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), folderPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create history folder: %v", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(printsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(countersBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize history database: %v", err)
+	}
+
+	logger.Info("History database opened: %s", path)
+	return &Store{db: db, reserved: make(map[string]map[uint64]bool)}, nil
+}
+
+// Stores a new entry and assigns its ID
+func (s *Store) Add(entry *Entry) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(printsBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		entry.ID = id
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode history entry: %v", err)
+		}
+
+		return bucket.Put(itob(id), data)
+	})
+}
+
+// Returns up to n of the most recent entries of a user, newest first
+func (s *Store) Recent(userID int64, n int) ([]Entry, error) {
+	return s.latest(n, func(entry *Entry) bool { return entry.UserID == userID })
+}
+
+// Returns up to n of the most recent entries of all users, newest first
+func (s *Store) Latest(n int) ([]Entry, error) {
+	return s.latest(n, func(*Entry) bool { return true })
+}
+
+// Returns up to n of the most recent entries accepted by match, newest first
+func (s *Store) latest(n int, match func(*Entry) bool) ([]Entry, error) {
+	entries := make([]Entry, 0, n)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(printsBucket).Cursor()
+
+		for key, value := cursor.Last(); key != nil && len(entries) < n; key, value = cursor.Prev() {
+			var entry Entry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				logger.Warn("Skipping unreadable history entry %d: %v", binary.BigEndian.Uint64(key), err)
+				continue
+			}
+
+			if match(&entry) {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	return entries, nil
+}
+
+// Records a finished print job, meant to be registered with Printer.OnJobFinished
+// Commits the counter value of a printed label and releases the one of a failed label
+func (s *Store) RecordJob(job *printers.Job) {
+	if job.Kind != printers.JobPrint {
+		return
+	}
+
+	entry := &Entry{
+		UserID:    job.Meta.UserID,
+		ChatID:    job.Meta.ChatID,
+		Username:  job.Meta.Username,
+		Preset:    job.Meta.Preset,
+		Printer:   job.Printer,
+		Label:     job.Label,
+		Timestamp: job.CreatedAt,
+		Outcome:   OutcomeSuccess,
+	}
+	if len(entry.Label.Image) > MaxImageBytes {
+		entry.Label.Image = nil
+		entry.ImageDropped = true
+	}
+
+	if err := job.Err(); err != nil {
+		entry.Outcome = OutcomeFailed
+		entry.Error = err.Error()
+	}
+
+	if err := s.Add(entry); err != nil {
+		logger.Error("Failed to record print history for job %d: %v", job.ID, err)
+	}
+
+	if job.Meta.Counter == 0 {
+		return
+	}
+	if job.Err() != nil {
+		s.releaseCounter(job.Meta.Preset, job.Meta.Counter)
+		return
+	}
+	if err := s.commitCounter(job.Meta.Preset, job.Meta.Counter); err != nil {
+		logger.Error("Failed to store counter of job %d: %v", job.ID, err)
+	}
+}
+
+// Counter value used by one label, taken when the label's template first uses the counter
+// A printed label commits the value through RecordJob, a failed job gives it back,
+// so the stored counter only advances for labels that were printed
+type Reservation struct {
+	store *Store
+	name  string
+	peek  bool
+	value uint64
+}
+
+// Returns a reservation of the named counter for templates.Data
+// Previews only peek at the next value and reserve nothing
+// Works on a nil store, using the counter then reports that counters are unavailable
+func (s *Store) ReserveCounter(name string, peek bool) *Reservation {
+	return &Reservation{store: s, name: name, peek: peek}
+}
+
+// Returns the value of the label, reserving it on the first call
+// Meant as templates.Data.NextCounter, a template using the counter twice gets the same value
+func (r *Reservation) Next() (uint64, error) {
+	if r.store == nil {
+		return 0, fmt.Errorf("counters need the print history database")
+	}
+	if r.value != 0 {
+		return r.value, nil
+	}
+
+	if r.peek {
+		return r.store.PeekCounter(r.name)
+	}
+	value, err := r.store.reserveCounter(r.name)
+	if err != nil {
+		return 0, err
+	}
+	r.value = value
+	return value, nil
+}
+
+// Returns the reserved value for printers.JobMeta.Counter, 0 if nothing was reserved
+func (r *Reservation) Value() uint64 {
+	return r.value
+}
+
+// Gives the value back, for labels that are not submitted after all
+func (r *Reservation) Release() {
+	if r.store != nil && r.value != 0 {
+		r.store.releaseCounter(r.name, r.value)
+		r.value = 0
+	}
+}
+
+// Returns the next value of the named counter that is neither stored nor reserved
+func (s *Store) reserveCounter(name string) (uint64, error) {
+	s.counterMutex.Lock()
+	defer s.counterMutex.Unlock()
+
+	value, err := s.storedCounter(name)
+	if err != nil {
+		return 0, err
+	}
+	for reserved := range s.reserved[name] {
+		value = max(value, reserved)
+	}
+	value++
+
+	if s.reserved[name] == nil {
+		s.reserved[name] = make(map[uint64]bool)
+	}
+	s.reserved[name][value] = true
+	return value, nil
+}
+
+// Drops a reservation without storing it
+func (s *Store) releaseCounter(name string, value uint64) {
+	s.counterMutex.Lock()
+	defer s.counterMutex.Unlock()
+	delete(s.reserved[name], value)
+}
+
+// Stores a reserved value as the named counter, unless the counter is already higher
+func (s *Store) commitCounter(name string, value uint64) error {
+	s.counterMutex.Lock()
+	defer s.counterMutex.Unlock()
+	delete(s.reserved[name], value)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(countersBucket)
+		if current := bucket.Get([]byte(name)); current != nil && binary.BigEndian.Uint64(current) >= value {
+			return nil
+		}
+		return bucket.Put([]byte(name), itob(value))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update counter '%s': %v", name, err)
+	}
+	return nil
+}
+
+// Returns the stored value of the named counter, 0 if it was never used
+func (s *Store) storedCounter(name string) (uint64, error) {
+	var value uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if current := tx.Bucket(countersBucket).Get([]byte(name)); current != nil {
+			value = binary.BigEndian.Uint64(current)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to read counter '%s': %v", name, err)
+	}
+	return value, nil
+}
+
+// Returns the value the next printed label of the named counter gets, without reserving it
+func (s *Store) PeekCounter(name string) (uint64, error) {
+	s.counterMutex.Lock()
+	defer s.counterMutex.Unlock()
+
+	value, err := s.storedCounter(name)
+	if err != nil {
+		return 0, err
+	}
+	for reserved := range s.reserved[name] {
+		value = max(value, reserved)
+	}
+	return value + 1, nil
+}
+
+// Closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Encodes a sequence number as a sortable big-endian key
+func itob(v uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, v)
+	return key
+}
@@ -0,0 +1,129 @@
+package history
+
+import (
+	"brother-cube-telegram/printers"
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"), 0700)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestCounterReservations(t *testing.T) {
+	store := openTestStore(t)
+	next := func() *Reservation {
+		t.Helper()
+		counter := store.ReserveCounter("box", false)
+		if _, err := counter.Next(); err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		return counter
+	}
+	peek := func() uint64 {
+		t.Helper()
+		value, err := store.ReserveCounter("box", true).Next()
+		if err != nil {
+			t.Fatalf("peek: %v", err)
+		}
+		return value
+	}
+
+	first := next()
+	if first.Value() != 1 || peek() != 2 {
+		t.Fatalf("first value %d, peek %d", first.Value(), peek())
+	}
+
+	// A queued label keeps its number, the next one does not reuse it
+	second := next()
+	if second.Value() != 2 {
+		t.Fatalf("second value %d, expected 2", second.Value())
+	}
+	if value, _ := second.Next(); value != 2 {
+		t.Fatalf("repeated Next returned %d, expected 2", value)
+	}
+
+	// What RecordJob does for a printed and a failed job
+	if err := store.commitCounter("box", first.Value()); err != nil {
+		t.Fatalf("commitCounter: %v", err)
+	}
+	store.releaseCounter("box", second.Value())
+	if stored, _ := store.storedCounter("box"); stored != 1 {
+		t.Fatalf("stored counter %d after one printed label, expected 1", stored)
+	}
+
+	// The number of the failed job is used again
+	third := next()
+	if third.Value() != 2 {
+		t.Fatalf("value after failed job %d, expected 2", third.Value())
+	}
+	third.Release()
+	if peek() != 2 {
+		t.Fatalf("peek after release %d, expected 2", peek())
+	}
+}
+
+func TestPreviewDoesNotReserve(t *testing.T) {
+	store := openTestStore(t)
+
+	preview := store.ReserveCounter("box", true)
+	if value, err := preview.Next(); err != nil || value != 1 {
+		t.Fatalf("preview value %d, %v", value, err)
+	}
+	if preview.Value() != 0 {
+		t.Fatalf("preview reserved %d", preview.Value())
+	}
+	if value, _ := store.ReserveCounter("box", false).Next(); value != 1 {
+		t.Fatalf("print after preview got %d, expected 1", value)
+	}
+}
+
+func TestCounterWithoutStore(t *testing.T) {
+	var store *Store
+	counter := store.ReserveCounter("box", false)
+	if _, err := counter.Next(); err == nil {
+		t.Fatal("counter without history database did not fail")
+	}
+	counter.Release()
+}
+
+func TestRecordJobDropsLargeImages(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		reprintable bool
+	}{
+		{"small image", 1024, true},
+		{"largest kept image", MaxImageBytes, true},
+		{"too large", MaxImageBytes + 1, false},
+	}
+
+	store := openTestStore(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			label := printers.Label{Text: "Photo", Image: make([]byte, tt.size)}
+			store.RecordJob(&printers.Job{Kind: printers.JobPrint, Label: label})
+
+			entries, err := store.Latest(1)
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("Latest: %d entries, %v", len(entries), err)
+			}
+			entry := entries[0]
+			if entry.Reprintable() != tt.reprintable {
+				t.Fatalf("Reprintable() = %v, expected %v", entry.Reprintable(), tt.reprintable)
+			}
+			if stored := len(entry.Label.Image); tt.reprintable && stored != tt.size || !tt.reprintable && stored != 0 {
+				t.Errorf("stored %d image bytes", stored)
+			}
+			if text := entry.DisplayText(); text != "[image] Photo" {
+				t.Errorf("DisplayText() = %q", text)
+			}
+		})
+	}
+}
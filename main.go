@@ -7,14 +7,23 @@ import (
 
 	_ "github.com/joho/godotenv/autoload"
 
+	"brother-cube-telegram/api"
 	"brother-cube-telegram/config"
 	"brother-cube-telegram/gpio"
+	"brother-cube-telegram/history"
 	"brother-cube-telegram/logger"
+	"brother-cube-telegram/metrics"
+	"brother-cube-telegram/mqtt"
 	"brother-cube-telegram/printers"
 	"brother-cube-telegram/telegram"
 )
 
 func main() {
+	// Subcommands like print or status run without starting the bot
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	// Load configuration
 	if err := config.LoadDefault(); err != nil {
 		logger.Error("Failed to load configuration: %v", err)
@@ -33,27 +42,88 @@ func main() {
 		}
 	}()
 
-	logger.Info("Testing relay on GPIO%d...", cfg.GPIO.RelayPin)
-	relay, err := gpio.NewRelay(cfg.GPIO.RelayPin)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	// Opened before the printers so it is closed after them, jobs finishing during shutdown are still recorded
+	historyStore, err := history.Open(cfg.History.Path, cfg.Printer.GetFolderPermissions())
 	if err != nil {
-		logger.Error("Failed to initialize relay: %v", err)
+		logger.Error("Failed to open print history: %v", err)
 	} else {
-		defer relay.Close()
+		defer historyStore.Close()
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer cancel()
+	pool := printers.NewPool(cfg.Routing)
+	for i := range cfg.Printers {
+		printerCfg := &cfg.Printers[i]
+
+		var relay gpio.Switch
+		if printerCfg.GPIO != nil {
+			logger.Info("Testing relay of printer '%s' on %s...", printerCfg.Name, printerCfg.GPIO)
+			var err error
+			relay, err = gpio.NewSwitch(printerCfg.GPIO)
+			if err != nil {
+				logger.Error("Failed to initialize relay of printer '%s': %v", printerCfg.Name, err)
+			} else {
+				defer relay.Close()
+			}
+		}
+
+		printer := printers.NewPrinter(ctx, printerCfg.Name, &printerCfg.PrinterConfig, relay)
+		if printer == nil {
+			logger.Error("Printer '%s' is not available", printerCfg.Name)
+		}
+		pool.Add(printerCfg.Name, printer)
+	}
+	defer pool.Close()
 
-	printer := printers.NewPrinter(relay)
-	if printer != nil {
-		defer printer.Close()
+	// Add printers to context
+	ctx = context.WithValue(ctx, "printers", pool)
+
+	if historyStore != nil {
+		pool.OnJobFinished(historyStore.RecordJob)
+
+		// Add history store to context
+		ctx = context.WithValue(ctx, "history", historyStore)
+	}
+
+	// Publish the printers to Home Assistant and accept commands over MQTT
+	if cfg.MQTT.Enabled {
+		bridge, err := mqtt.Start(ctx, &cfg.MQTT, pool, historyStore)
+		if err != nil {
+			logger.Error("Failed to start MQTT: %v", err)
+		} else {
+			defer bridge.Close()
+		}
+	}
+
+	// Let scripts on the local network print over HTTP
+	if cfg.API.Enabled {
+		server, err := api.Start(ctx, &cfg.API, pool, historyStore)
+		if err != nil {
+			logger.Error("Failed to start HTTP API: %v", err)
+		} else {
+			defer server.Close()
+		}
 	}
 
-	// Add printer to context
-	ctx = context.WithValue(ctx, "printer", printer)
+	// Expose counters and durations to Prometheus
+	if cfg.Metrics.Enabled {
+		server, err := metrics.Start(&cfg.Metrics)
+		if err != nil {
+			logger.Error("Failed to start metrics server: %v", err)
+		} else {
+			defer server.Close()
+		}
+	}
 
 	b := telegram.GetBot(ctx)
 
+	// Send printer monitor alerts to the admin chats
+	if notifier := telegram.AlertNotifier(ctx, b); notifier != nil {
+		pool.OnAlert(notifier)
+	}
+
 	logger.Info("Bot started successfully. Press Ctrl+C to stop.")
 
 	// Start the bot (this blocks until context is cancelled)
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/logger"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Time given to a running scrape when the server shuts down
+const shutdownTimeout = 5 * time.Second
+
+// Serves the Prometheus metrics registered by the other packages
+type Server struct {
+	server *http.Server
+}
+
+// Starts the metrics server in the background
+// Fails if the address cannot be used
+func Start(cfg *config.MetricsConfig) (*Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	s := &Server{
+		server: &http.Server{
+			Addr:              cfg.GetListen(),
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", s.server.Addr, err)
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Metrics server stopped: %v", err)
+		}
+	}()
+
+	logger.Info("Metrics available on http://%s/metrics", listener.Addr())
+	return s, nil
+}
+
+// Stops the server
+func (s *Server) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		logger.Error("Error stopping metrics server: %v", err)
+	}
+	logger.Info("Metrics server stopped")
+}
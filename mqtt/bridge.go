@@ -0,0 +1,210 @@
+package mqtt
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/history"
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Quality of service of all messages, state updates and commands must not get lost
+const qos = 1
+
+// Time to wait for the broker when connecting and when sending the offline state
+const brokerTimeout = 10 * time.Second
+
+// Characters that are not allowed in topic levels and Home Assistant object IDs
+var invalidTopicChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Publishes the printers to an MQTT broker and runs the print and power commands received from it
+// Home Assistant picks the printers up through MQTT discovery
+type Bridge struct {
+	ctx     context.Context
+	config  *config.MQTTConfig
+	history *history.Store
+	client  paho.Client
+	prefix  string
+
+	// Available printers by their topic level
+	printers map[string]*printers.Printer
+
+	closed atomic.Bool
+	done   chan struct{}
+}
+
+// Connects to the broker and starts publishing the available printers of the pool
+// A broker that is not reachable yet is retried in the background
+// store may be nil, presets with counters then fail
+func Start(ctx context.Context, cfg *config.MQTTConfig, pool *printers.Pool, store *history.Store) (*Bridge, error) {
+	b, err := newBridge(ctx, cfg, pool, store)
+	if err != nil {
+		return nil, err
+	}
+
+	options := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.GetClientID()).
+		SetUsername(os.Getenv("MQTT_USERNAME")).
+		SetPassword(os.Getenv("MQTT_PASSWORD")).
+		SetWill(b.availabilityTopic(), "offline", qos, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectTimeout(brokerTimeout).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logger.Warn("MQTT connection lost: %v", err)
+		})
+
+	b.client = paho.NewClient(options)
+	token := b.client.Connect()
+	if token.WaitTimeout(brokerTimeout) && token.Error() != nil {
+		return nil, fmt.Errorf("failed to connect to MQTT broker %s: %v", cfg.Broker, token.Error())
+	}
+	if !b.client.IsConnected() {
+		logger.Warn("MQTT broker %s is not reachable yet, retrying in the background", cfg.Broker)
+	}
+
+	b.listen(pool)
+	go b.publishLoop()
+
+	return b, nil
+}
+
+// Creates the bridge for the available printers of the pool without connecting it
+// Fails if two printer names map to the same topic level, their commands would be mixed up
+func newBridge(ctx context.Context, cfg *config.MQTTConfig, pool *printers.Pool, store *history.Store) (*Bridge, error) {
+	b := &Bridge{
+		ctx:      ctx,
+		config:   cfg,
+		history:  store,
+		prefix:   cfg.GetTopicPrefix(),
+		printers: make(map[string]*printers.Printer),
+		done:     make(chan struct{}),
+	}
+
+	for _, printer := range pool.Available() {
+		level := topicLevel(printer.Name())
+		if other, exists := b.printers[level]; exists {
+			return nil, fmt.Errorf("printers '%s' and '%s' share the MQTT topic %s/%s, rename one of them", other.Name(), printer.Name(), b.prefix, level)
+		}
+		b.printers[level] = printer
+	}
+	return b, nil
+}
+
+// Publishes the state of a printer when a job starts, and the result of print jobs
+func (b *Bridge) listen(pool *printers.Pool) {
+	pool.OnJobStarted(func(job *printers.Job) {
+		b.publishState(job.Printer)
+	})
+	pool.OnJobFinished(func(job *printers.Job) {
+		if job.Kind == printers.JobPrint {
+			b.publishJob(job.Printer, jobStateFromJob(job))
+		}
+		b.publishState(job.Printer)
+	})
+}
+
+// Publishes the offline state and disconnects from the broker
+func (b *Bridge) Close() {
+	if b.closed.Swap(true) {
+		return
+	}
+	close(b.done)
+
+	if b.client.IsConnected() {
+		b.client.Publish(b.availabilityTopic(), qos, true, "offline").WaitTimeout(brokerTimeout)
+	}
+	b.client.Disconnect(250)
+	logger.Info("MQTT disconnected")
+}
+
+// Announces the printers, subscribes to the command topics and publishes the current state
+// Runs after every (re)connect, retained messages and subscriptions are restored this way
+func (b *Bridge) onConnect(client paho.Client) {
+	logger.Info("MQTT connected to %s", b.config.Broker)
+
+	client.Publish(b.availabilityTopic(), qos, true, "online")
+
+	for id, printer := range b.printers {
+		for _, entity := range discoveryEntities(b, id, printer) {
+			b.publish(entity.topic, entity.payload)
+		}
+	}
+
+	filters := map[string]byte{
+		b.prefix + "/+/print":     qos,
+		b.prefix + "/+/power/set": qos,
+	}
+	if token := client.SubscribeMultiple(filters, b.onCommand); token.WaitTimeout(brokerTimeout) && token.Error() != nil {
+		logger.Error("Failed to subscribe to MQTT command topics: %v", token.Error())
+	}
+
+	for _, printer := range b.printers {
+		b.publishState(printer.Name())
+	}
+}
+
+// Publishes the state of all printers at the configured interval
+func (b *Bridge) publishLoop() {
+	ticker := time.NewTicker(b.config.GetPublishInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, printer := range b.printers {
+			b.publishState(printer.Name())
+		}
+	}
+}
+
+// Topic with the online/offline state of the bridge
+func (b *Bridge) availabilityTopic() string {
+	return b.prefix + "/status"
+}
+
+// Topic of a printer, e.g. brother-cube/default/power
+func (b *Bridge) topic(printerName string, levels ...string) string {
+	return strings.Join(append([]string{b.prefix, topicLevel(printerName)}, levels...), "/")
+}
+
+// Publishes a retained message, payloads other than strings are sent as JSON
+func (b *Bridge) publish(topic string, payload any) {
+	if b.closed.Load() || !b.client.IsConnected() {
+		return
+	}
+
+	if _, ok := payload.(string); !ok {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			logger.Error("Failed to encode MQTT message for %s: %v", topic, err)
+			return
+		}
+		payload = data
+	}
+
+	// Do not wait for the broker, publishing runs from job listeners
+	b.client.Publish(topic, qos, true, payload)
+}
+
+// Returns the topic level for a printer name, e.g. "Office 2" becomes "Office_2"
+func topicLevel(name string) string {
+	return invalidTopicChars.ReplaceAllString(name, "_")
+}
@@ -0,0 +1,323 @@
+package mqtt
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/gpio"
+	"brother-cube-telegram/printers"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Broker stand-in: keeps the retained messages and delivers published commands to the subscription
+// Methods the bridge does not use panic through the nil embedded client
+type fakeBroker struct {
+	paho.Client
+
+	mu       sync.Mutex
+	retained map[string][]byte
+	filters  map[string]byte
+	handler  paho.MessageHandler
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{retained: make(map[string][]byte)}
+}
+
+func (f *fakeBroker) IsConnected() bool {
+	return true
+}
+
+func (f *fakeBroker) Publish(topic string, _ byte, _ bool, payload any) paho.Token {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch data := payload.(type) {
+	case string:
+		f.retained[topic] = []byte(data)
+	case []byte:
+		f.retained[topic] = data
+	}
+	return doneToken{}
+}
+
+func (f *fakeBroker) SubscribeMultiple(filters map[string]byte, handler paho.MessageHandler) paho.Token {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.filters = filters
+	f.handler = handler
+	return doneToken{}
+}
+
+// Sends a command to the bridge like a client publishing on the topic
+func (f *fakeBroker) send(topic, payload string) {
+	f.mu.Lock()
+	handler := f.handler
+	f.mu.Unlock()
+	handler(f, &fakeMessage{topic: topic, payload: []byte(payload)})
+}
+
+// Returns the retained message of a topic
+func (f *fakeBroker) message(topic string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payload, ok := f.retained[topic]
+	return string(payload), ok
+}
+
+// Waits until the retained message of a topic is the expected one
+func (f *fakeBroker) waitFor(t *testing.T, topic string, expected func(string) bool) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		payload, ok := f.message(topic)
+		if ok && expected(payload) {
+			return payload
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no expected message on %s, last: %q", topic, payload)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type doneToken struct{}
+
+func (doneToken) Wait() bool                     { return true }
+func (doneToken) WaitTimeout(time.Duration) bool { return true }
+func (doneToken) Error() error                   { return nil }
+func (doneToken) Done() <-chan struct{} {
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+type fakeMessage struct {
+	paho.Message
+	topic   string
+	payload []byte
+}
+
+func (m *fakeMessage) Topic() string   { return m.topic }
+func (m *fakeMessage) Payload() []byte { return m.payload }
+
+// Creates a printer with the file backend, powered by a simulated relay if withRelay is set
+func newTestPrinter(t *testing.T, name string, withRelay bool) *printers.Printer {
+	t.Helper()
+	cfg := &config.PrinterConfig{
+		Backend:     config.BackendFile,
+		FileBackend: config.FileBackendConfig{OutputFolder: t.TempDir()},
+		MaxLines:    printers.MaxLabelLines,
+		// Keeps the printer on between the commands of a test
+		AutoShutdownDelayMinutes: 10,
+	}
+
+	var relay gpio.Switch
+	if withRelay {
+		relay = gpio.NewSimulatedRelay(17, false)
+	}
+	printer := printers.NewPrinter(context.Background(), name, cfg, relay)
+	if printer == nil {
+		t.Fatalf("printer '%s' could not be created", name)
+	}
+	t.Cleanup(func() { printer.Close() })
+	return printer
+}
+
+// Starts a bridge for the printers connected to a broker stand-in
+func newTestBridge(t *testing.T, available ...*printers.Printer) (*Bridge, *fakeBroker) {
+	t.Helper()
+	pool := printers.NewPool(config.RoutingConfig{})
+	for _, printer := range available {
+		pool.Add(printer.Name(), printer)
+	}
+
+	b, err := newBridge(context.Background(), &config.MQTTConfig{Enabled: true}, pool, nil)
+	if err != nil {
+		t.Fatalf("newBridge: %v", err)
+	}
+	t.Cleanup(func() { b.closed.Store(true) })
+
+	broker := newFakeBroker()
+	b.client = broker
+	b.listen(pool)
+	b.onConnect(broker)
+	return b, broker
+}
+
+func TestNewBridgeRejectsTopicCollision(t *testing.T) {
+	pool := printers.NewPool(config.RoutingConfig{})
+	pool.Add("Office 2", newTestPrinter(t, "Office 2", false))
+	pool.Add("Office_2", newTestPrinter(t, "Office_2", false))
+
+	_, err := newBridge(context.Background(), &config.MQTTConfig{}, pool, nil)
+	if err == nil || !strings.Contains(err.Error(), "brother-cube/Office_2") {
+		t.Fatalf("expected a topic collision error, got %v", err)
+	}
+}
+
+func TestOnConnectSubscribesAndPublishesState(t *testing.T) {
+	_, broker := newTestBridge(t, newTestPrinter(t, "Office 2", false))
+
+	if payload, _ := broker.message("brother-cube/status"); payload != "online" {
+		t.Errorf("availability is %q, expected online", payload)
+	}
+	for _, filter := range []string{"brother-cube/+/print", "brother-cube/+/power/set"} {
+		if _, ok := broker.filters[filter]; !ok {
+			t.Errorf("not subscribed to %s", filter)
+		}
+	}
+	if payload, _ := broker.message("brother-cube/Office_2/power"); payload != payloadOn {
+		t.Errorf("power of a printer without relay is %q, expected ON", payload)
+	}
+}
+
+func TestDiscoveryPayloads(t *testing.T) {
+	printer := newTestPrinter(t, "Office 2", false)
+	b, broker := newTestBridge(t, printer)
+
+	entities := discoveryEntities(b, "Office_2", printer)
+	tests := []struct {
+		topic        string
+		stateTopic   string
+		commandTopic string
+	}{
+		{"homeassistant/switch/brother-cube-telegram/Office_2_power/config", "brother-cube/Office_2/power", "brother-cube/Office_2/power/set"},
+		{"homeassistant/binary_sensor/brother-cube-telegram/Office_2_busy/config", "brother-cube/Office_2/busy", ""},
+		{"homeassistant/sensor/brother-cube-telegram/Office_2_last_job/config", "brother-cube/Office_2/last_job", ""},
+		{"homeassistant/sensor/brother-cube-telegram/Office_2_tape/config", "brother-cube/Office_2/tape", ""},
+		{"homeassistant/text/brother-cube-telegram/Office_2_print/config", "", "brother-cube/Office_2/print"},
+	}
+	if len(entities) != len(tests) {
+		t.Fatalf("got %d entities, expected %d", len(entities), len(tests))
+	}
+
+	uniqueIDs := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			data, ok := broker.message(tt.topic)
+			if !ok {
+				t.Fatal("discovery message not published")
+			}
+			var payload struct {
+				UniqueID          string `json:"unique_id"`
+				StateTopic        string `json:"state_topic"`
+				CommandTopic      string `json:"command_topic"`
+				AvailabilityTopic string `json:"availability_topic"`
+				Device            struct {
+					Identifiers []string `json:"identifiers"`
+					Model       string   `json:"model"`
+				} `json:"device"`
+			}
+			if err := json.Unmarshal([]byte(data), &payload); err != nil {
+				t.Fatalf("invalid payload: %v", err)
+			}
+
+			if payload.StateTopic != tt.stateTopic || payload.CommandTopic != tt.commandTopic {
+				t.Errorf("state/command topic %q/%q, expected %q/%q", payload.StateTopic, payload.CommandTopic, tt.stateTopic, tt.commandTopic)
+			}
+			if payload.AvailabilityTopic != "brother-cube/status" {
+				t.Errorf("availability topic %q", payload.AvailabilityTopic)
+			}
+			if len(payload.Device.Identifiers) != 1 || payload.Device.Identifiers[0] != "brother-cube-telegram_Office_2" {
+				t.Errorf("device identifiers %v", payload.Device.Identifiers)
+			}
+			if payload.Device.Model != "File backend" {
+				t.Errorf("device model %q", payload.Device.Model)
+			}
+			if uniqueIDs[payload.UniqueID] {
+				t.Errorf("unique_id %q used twice", payload.UniqueID)
+			}
+			uniqueIDs[payload.UniqueID] = true
+		})
+	}
+}
+
+func TestPrintCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		state   printers.JobState
+		text    string
+		error   string
+	}{
+		{"plain text", "Hello", printers.JobDone, "Hello", ""},
+		{"json", `{"text": "Jam"}`, printers.JobDone, "Jam", ""},
+		{"invalid json", `{"text": `, printers.JobFailed, "", "invalid print command"},
+		{"too many lines", "a\nb\nc\nd\ne", printers.JobFailed, "", "lines"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, broker := newTestBridge(t, newTestPrinter(t, "office", false))
+
+			broker.send("brother-cube/office/print", tt.payload)
+			data := broker.waitFor(t, "brother-cube/office/last_job", func(string) bool { return true })
+
+			var state jobState
+			if err := json.Unmarshal([]byte(data), &state); err != nil {
+				t.Fatalf("invalid last job: %v", err)
+			}
+			if state.State != string(tt.state) || state.User != mqttUser {
+				t.Errorf("last job %+v, expected state %s", state, tt.state)
+			}
+			if tt.text != "" && state.Text != tt.text {
+				t.Errorf("text %q, expected %q", state.Text, tt.text)
+			}
+			if !strings.Contains(state.Error, tt.error) {
+				t.Errorf("error %q, expected %q", state.Error, tt.error)
+			}
+		})
+	}
+}
+
+func TestPrintCommandRoutesByTopic(t *testing.T) {
+	office := newTestPrinter(t, "Office 2", false)
+	garage := newTestPrinter(t, "garage", false)
+	_, broker := newTestBridge(t, office, garage)
+
+	broker.send("brother-cube/garage/print", "Screws")
+	broker.waitFor(t, "brother-cube/garage/last_job", func(payload string) bool {
+		return strings.Contains(payload, "Screws")
+	})
+	if _, ok := broker.message("brother-cube/Office_2/last_job"); ok {
+		t.Error("job of garage was published for Office 2")
+	}
+
+	// Commands for unknown printers are dropped
+	broker.send("brother-cube/attic/print", "Boxes")
+	if _, ok := broker.message("brother-cube/attic/last_job"); ok {
+		t.Error("job was published for an unknown printer")
+	}
+}
+
+func TestPowerCommand(t *testing.T) {
+	printer := newTestPrinter(t, "office", true)
+	_, broker := newTestBridge(t, printer)
+
+	tests := []struct {
+		payload string
+		power   string
+	}{
+		{"OFF", payloadOff},
+		{"on", payloadOn},
+		{"toggle", payloadOn},
+		{"off", payloadOff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.payload, func(t *testing.T) {
+			broker.send("brother-cube/office/power/set", tt.payload)
+			broker.waitFor(t, "brother-cube/office/power", func(payload string) bool {
+				return payload == tt.power
+			})
+			if on := printer.Power().IsOn(); on != (tt.power == payloadOn) {
+				t.Errorf("printer power is %v after %s", on, tt.payload)
+			}
+		})
+	}
+}
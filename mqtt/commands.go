@@ -0,0 +1,122 @@
+package mqtt
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/history"
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/templates"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Name recorded as the user of jobs received over MQTT
+const mqttUser = "mqtt"
+
+// Payload of <prefix>/<printer>/print, plain text is accepted as well
+type printCommand struct {
+	Text   string `json:"text"`
+	Preset string `json:"preset"`
+}
+
+// Dispatches a message from one of the command topics
+// Commands run in their own goroutine, paho delivers messages one at a time
+func (b *Bridge) onCommand(_ paho.Client, message paho.Message) {
+	levels := strings.Split(strings.TrimPrefix(message.Topic(), b.prefix+"/"), "/")
+	printer := b.printers[levels[0]]
+	if printer == nil {
+		logger.Warn("MQTT command for unknown printer on %s", message.Topic())
+		return
+	}
+
+	payload := strings.TrimSpace(string(message.Payload()))
+	switch strings.Join(levels[1:], "/") {
+	case "print":
+		go b.print(printer, payload)
+	case "power/set":
+		go b.setPower(printer, payload)
+	}
+}
+
+// Prints the text or JSON print command, the result is published as the last job
+func (b *Bridge) print(printer *printers.Printer, payload string) {
+	command := printCommand{Text: payload}
+	if strings.HasPrefix(payload, "{") {
+		command = printCommand{}
+		if err := json.Unmarshal([]byte(payload), &command); err != nil {
+			b.rejectPrint(printer, payload, "", fmt.Errorf("invalid print command: %v", err))
+			return
+		}
+	}
+
+	counter := b.history.ReserveCounter(command.Preset, false)
+	label, err := b.label(printer, command, counter)
+	if err != nil {
+		counter.Release()
+		b.rejectPrint(printer, command.Text, command.Preset, err)
+		return
+	}
+
+	// The job listener publishes the result once the job is done
+	logger.Info("Printing MQTT label on '%s': %s", printer.Name(), label.DisplayText())
+	meta := printers.JobMeta{Username: mqttUser, Preset: command.Preset, Counter: counter.Value()}
+	if _, err := printer.Submit(printers.JobPrint, label, meta); err != nil {
+		counter.Release()
+		b.rejectPrint(printer, command.Text, command.Preset, err)
+	}
+}
+
+// Builds the label of a print command with the printer defaults or a preset
+// A preset template takes its counter value from the reservation
+func (b *Bridge) label(printer *printers.Printer, command printCommand, counter *history.Reservation) (printers.Label, error) {
+	if command.Preset == "" {
+		return printer.DefaultLabel(command.Text)
+	}
+
+	preset := config.Get().Printer.GetPreset(command.Preset)
+	if preset == nil {
+		return printers.Label{}, fmt.Errorf("preset '%s' not found", command.Preset)
+	}
+
+	return printer.TemplatePresetLabel(preset, templates.Data{
+		Text:        strings.TrimSpace(command.Text),
+		User:        mqttUser,
+		Preset:      command.Preset,
+		NextCounter: counter.Next,
+	})
+}
+
+// Publishes a print command that could not be queued as a failed job
+func (b *Bridge) rejectPrint(printer *printers.Printer, text, preset string, err error) {
+	logger.Error("MQTT print command for '%s' failed: %v", printer.Name(), err)
+	b.publishJob(printer.Name(), jobState{
+		State:  string(printers.JobFailed),
+		Text:   text,
+		User:   mqttUser,
+		Preset: preset,
+		Error:  err.Error(),
+		Time:   time.Now(),
+	})
+}
+
+// Switches the printer on (waiting until it answers) or off
+func (b *Bridge) setPower(printer *printers.Printer, payload string) {
+	var err error
+	switch strings.ToUpper(payload) {
+	case payloadOn:
+		_, err = printer.GetPrinterInfo()
+	case payloadOff:
+		err = printer.Power().PowerOff()
+	default:
+		err = fmt.Errorf("unknown power command '%s', use %s or %s", payload, payloadOn, payloadOff)
+	}
+	if err != nil {
+		logger.Error("MQTT power command for '%s' failed: %v", printer.Name(), err)
+	}
+
+	b.publishState(printer.Name())
+}
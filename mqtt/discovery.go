@@ -0,0 +1,77 @@
+package mqtt
+
+import (
+	"brother-cube-telegram/printers"
+	"fmt"
+)
+
+// A discovery message announcing one Home Assistant entity
+type discoveryEntity struct {
+	topic   string
+	payload map[string]any
+}
+
+// Returns the Home Assistant entities of a printer: power switch, busy sensor,
+// last job and tape sensors, and a text entity that prints what is entered
+func discoveryEntities(b *Bridge, id string, printer *printers.Printer) []discoveryEntity {
+	node := topicLevel(b.config.GetClientID())
+	name := printer.Name()
+
+	device := map[string]any{
+		"identifiers":  []string{fmt.Sprintf("%s_%s", node, id)},
+		"name":         fmt.Sprintf("Label printer %s", name),
+		"manufacturer": "Brother",
+	}
+	if info := printer.LastInfo(); info != nil && info.Model != "" {
+		device["model"] = info.Model
+	}
+
+	entity := func(component, object string, payload map[string]any) discoveryEntity {
+		payload["unique_id"] = fmt.Sprintf("%s_%s_%s", node, id, object)
+		payload["availability_topic"] = b.availabilityTopic()
+		payload["device"] = device
+		return discoveryEntity{
+			topic:   fmt.Sprintf("%s/%s/%s/%s_%s/config", b.config.GetDiscoveryPrefix(), component, node, id, object),
+			payload: payload,
+		}
+	}
+
+	return []discoveryEntity{
+		entity("switch", "power", map[string]any{
+			"name":          "Power",
+			"icon":          "mdi:power",
+			"state_topic":   b.topic(name, "power"),
+			"command_topic": b.topic(name, "power", "set"),
+			"payload_on":    payloadOn,
+			"payload_off":   payloadOff,
+		}),
+		entity("binary_sensor", "busy", map[string]any{
+			"name":         "Busy",
+			"device_class": "running",
+			"state_topic":  b.topic(name, "busy"),
+			"payload_on":   payloadOn,
+			"payload_off":  payloadOff,
+		}),
+		entity("sensor", "last_job", map[string]any{
+			"name":                  "Last job",
+			"icon":                  "mdi:label-outline",
+			"state_topic":           b.topic(name, "last_job"),
+			"value_template":        "{{ value_json.state }}",
+			"json_attributes_topic": b.topic(name, "last_job"),
+		}),
+		entity("sensor", "tape", map[string]any{
+			"name":                  "Tape width",
+			"icon":                  "mdi:tape-measure",
+			"unit_of_measurement":   "mm",
+			"state_topic":           b.topic(name, "tape"),
+			"value_template":        "{{ value_json.tape_width_mm }}",
+			"json_attributes_topic": b.topic(name, "tape"),
+		}),
+		entity("text", "print", map[string]any{
+			"name":          "Print label",
+			"icon":          "mdi:printer",
+			"command_topic": b.topic(name, "print"),
+			"max":           255,
+		}),
+	}
+}
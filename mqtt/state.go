@@ -0,0 +1,87 @@
+package mqtt
+
+import (
+	"brother-cube-telegram/printers"
+	"time"
+)
+
+// Payloads of the binary topics
+const (
+	payloadOn  = "ON"
+	payloadOff = "OFF"
+)
+
+// Published on <prefix>/<printer>/last_job after every print job
+type jobState struct {
+	ID     int64     `json:"id,omitempty"`
+	State  string    `json:"state"`
+	Text   string    `json:"text"`
+	Labels int       `json:"labels"`
+	User   string    `json:"user,omitempty"`
+	Preset string    `json:"preset,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Published on <prefix>/<printer>/tape with the last known printer info
+type tapeState struct {
+	Model       string   `json:"model"`
+	TapeWidthMM int      `json:"tape_width_mm"`
+	MediaType   string   `json:"media_type"`
+	TapeColor   string   `json:"tape_color"`
+	TextColor   string   `json:"text_color"`
+	Errors      []string `json:"errors"`
+}
+
+// Builds the last job message of a finished job
+func jobStateFromJob(job *printers.Job) jobState {
+	state := jobState{
+		ID:     job.ID,
+		State:  string(job.State()),
+		Text:   job.Label.DisplayText(),
+		Labels: job.Label.Total(),
+		User:   job.Meta.Username,
+		Preset: job.Meta.Preset,
+		Time:   time.Now(),
+	}
+	if err := job.Err(); err != nil {
+		state.Error = err.Error()
+	}
+	return state
+}
+
+// Publishes the power, busy and tape state of a printer
+func (b *Bridge) publishState(printerName string) {
+	printer := b.printers[topicLevel(printerName)]
+	if printer == nil {
+		return
+	}
+
+	power := payloadOff
+	if printer.Power().IsOn() {
+		power = payloadOn
+	}
+	b.publish(b.topic(printerName, "power"), power)
+
+	busy := payloadOff
+	if printer.PendingJobs() > 0 {
+		busy = payloadOn
+	}
+	b.publish(b.topic(printerName, "busy"), busy)
+
+	if info := printer.LastInfo(); info != nil {
+		b.publish(b.topic(printerName, "tape"), tapeState{
+			Model:       info.Model,
+			TapeWidthMM: info.TapeWidthMM,
+			MediaType:   info.MediaType,
+			TapeColor:   info.TapeColor,
+			TextColor:   info.TextColor,
+			Errors:      append([]string{}, info.Errors.Messages()...),
+		})
+	}
+}
+
+// Publishes the result of a print job
+func (b *Bridge) publishJob(printerName string, state jobState) {
+	b.publish(b.topic(printerName, "last_job"), state)
+}
@@ -0,0 +1,32 @@
+package printers
+
+import (
+	"brother-cube-telegram/config"
+	"context"
+	"fmt"
+)
+
+// Talks to the device that produces labels
+// Every call gives up when the context is cancelled
+type Backend interface {
+	// Returns the driver version
+	Version(ctx context.Context) (string, error)
+	// Returns information about the printer and tape, fails if it does not respond
+	Info(ctx context.Context) (*PrinterInfo, error)
+	// Prints the labels as one chain, placed on the tape according to the layout
+	Print(ctx context.Context, labels []Label, layout Layout) error
+	// Renders the chain of labels to a PNG file at the given path without printing it
+	RenderPNG(ctx context.Context, labels []Label, layout Layout, path string) error
+}
+
+// Creates the backend selected in the printer configuration
+func newBackend(cfg *config.PrinterConfig) (Backend, error) {
+	switch cfg.Backend {
+	case "", config.BackendPtouch:
+		return newPtouchBackend(cfg.GetCommandTimeout()), nil
+	case config.BackendFile:
+		return newFileBackend(&cfg.FileBackend, cfg.GetFolderPermissions())
+	default:
+		return nil, fmt.Errorf("unknown printer backend '%s'", cfg.Backend)
+	}
+}
@@ -0,0 +1,104 @@
+package printers
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/logger"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Print height used when none is configured, matching 12mm tape
+const defaultFilePrintHeight = 76
+
+// Tape width reported when none is configured
+const defaultFileTapeWidthMM = 12
+
+// Writes every label as a PNG file instead of printing it
+// Useful for development and CI machines without a P-touch attached
+type fileBackend struct {
+	folder  string
+	height  int
+	widthMM int
+
+	mu      sync.Mutex
+	counter int
+}
+
+func newFileBackend(cfg *config.FileBackendConfig, perm os.FileMode) (*fileBackend, error) {
+	if cfg.OutputFolder == "" {
+		return nil, fmt.Errorf("file backend requires an output folder")
+	}
+
+	if err := os.MkdirAll(cfg.OutputFolder, perm); err != nil {
+		return nil, fmt.Errorf("failed to create output folder: %v", err)
+	}
+
+	height := cfg.PrintHeight
+	if height <= 0 {
+		height = defaultFilePrintHeight
+	}
+
+	widthMM := cfg.TapeWidthMM
+	if widthMM <= 0 {
+		widthMM = defaultFileTapeWidthMM
+	}
+
+	return &fileBackend{
+		folder:  cfg.OutputFolder,
+		height:  height,
+		widthMM: widthMM,
+	}, nil
+}
+
+func (b *fileBackend) Version(ctx context.Context) (string, error) {
+	return "file backend", nil
+}
+
+func (b *fileBackend) Info(ctx context.Context) (*PrinterInfo, error) {
+	return &PrinterInfo{
+		Model:            "File backend",
+		PrinterMaxPixels: b.height,
+		TapeMaxPixels:    b.height,
+		TapeWidthMM:      b.widthMM,
+		MediaType:        "PNG files",
+		TapeColor:        "White",
+		TextColor:        "Black",
+		Raw:              fmt.Sprintf("File backend writing labels to %s (print height: %dpx)", b.folder, b.height),
+	}, nil
+}
+
+// Chained labels are written like any other, the file backend has no tape feed to skip
+func (b *fileBackend) Print(ctx context.Context, labels []Label, layout Layout) error {
+	b.mu.Lock()
+	b.counter++
+	name := fmt.Sprintf("label-%s-%04d.png", time.Now().Format("20060102-150405"), b.counter)
+	b.mu.Unlock()
+
+	path := filepath.Join(b.folder, name)
+	if err := b.RenderPNG(ctx, labels, layout, path); err != nil {
+		return fmt.Errorf("error printing label: %v", err)
+	}
+
+	logger.Debug("Label written to %s", path)
+	return nil
+}
+
+func (b *fileBackend) RenderPNG(ctx context.Context, labels []Label, layout Layout, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(labels) > 0 && labels[0].FontFamily != "" {
+		logger.Debug("File backend ignores font family '%s'", labels[0].FontFamily)
+	}
+
+	img, err := renderChain(labels, layout, b.height)
+	if err != nil {
+		return err
+	}
+	return writePNG(img, path)
+}
@@ -0,0 +1,175 @@
+package printers
+
+import (
+	"brother-cube-telegram/logger"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const print = "ptouch-print"
+
+// Time the process gets to exit after it was killed before its output is abandoned
+const killWaitDelay = 2 * time.Second
+
+// Prints through the ptouch-print command line tool
+type ptouchBackend struct {
+	// Deadline for a single ptouch-print invocation
+	timeout time.Duration
+}
+
+func newPtouchBackend(timeout time.Duration) *ptouchBackend {
+	return &ptouchBackend{timeout: timeout}
+}
+
+func (b *ptouchBackend) Version(ctx context.Context) (string, error) {
+	return b.exec(ctx, "version", "--version")
+}
+
+func (b *ptouchBackend) Info(ctx context.Context) (*PrinterInfo, error) {
+	output, err := b.exec(ctx, "info", infoCmdArg)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrinterInfo(output)
+}
+
+func (b *ptouchBackend) Print(ctx context.Context, labels []Label, layout Layout) error {
+	args, cleanup, err := b.chainArgs(labels, layout)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if layout.Chain {
+		args = append(args, chainCmdArg)
+	}
+
+	// The output of a failed command is part of the error
+	if _, err := b.exec(ctx, "print", args...); err != nil {
+		return fmt.Errorf("error printing label: %w", err)
+	}
+	return nil
+}
+
+func (b *ptouchBackend) RenderPNG(ctx context.Context, labels []Label, layout Layout, path string) error {
+	args, cleanup, err := b.chainArgs(labels, layout)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	args = append(args, writePngCmdArg, path)
+	if _, err := b.exec(ctx, "render", args...); err != nil {
+		return fmt.Errorf("error previewing label: %w", err)
+	}
+	return nil
+}
+
+// Builds the arguments for a chain of labels with their padding and cut marks
+// The returned cleanup function removes all temporary files
+func (b *ptouchBackend) chainArgs(labels []Label, layout Layout) ([]string, func(), error) {
+	var args []string
+	var cleanups []func()
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+
+	for i, label := range labels {
+		if layout.cutMarkBefore(i) {
+			args = append(args, cutmarkCmdArg)
+		}
+		if label.padBefore > 0 {
+			args = append(args, padCmdArg, strconv.Itoa(label.padBefore))
+		}
+
+		labelArgs, labelCleanup, err := b.labelArgs(label)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		cleanups = append(cleanups, labelCleanup)
+		args = append(args, labelArgs...)
+
+		if label.padAfter > 0 {
+			args = append(args, padCmdArg, strconv.Itoa(label.padAfter))
+		}
+	}
+
+	if layout.cutMarkAfter() {
+		args = append(args, cutmarkCmdArg)
+	}
+
+	return args, cleanup, nil
+}
+
+// Builds the arguments for a label, writing its image to a temporary file if needed
+// The returned cleanup function removes the temporary file
+func (b *ptouchBackend) labelArgs(label Label) ([]string, func(), error) {
+	if len(label.Image) == 0 {
+		return label.args(""), func() {}, nil
+	}
+
+	file, err := os.CreateTemp("", "label-image-*.png")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary image file: %v", err)
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+
+	_, err = file.Write(label.Image)
+	file.Close()
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to write temporary image file: %v", err)
+	}
+
+	return label.args(file.Name()), cleanup, nil
+}
+
+// Executes ptouch-print with the given arguments and returns the output
+// The whole process group is killed when the timeout expires or the context is cancelled
+// name identifies the kind of command in the duration metric
+func (b *ptouchBackend) exec(ctx context.Context, name string, arg ...string) (string, error) {
+	// Log the command being executed
+	logger.Debug("Executing command: %s %v", print, arg)
+
+	runCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	command := exec.CommandContext(runCtx, print, arg...)
+	killProcessGroupOnCancel(command)
+	command.WaitDelay = killWaitDelay
+
+	start := time.Now()
+	output, err := command.CombinedOutput()
+
+	// Every run is observed, cancelled and timed out ones under their own outcome
+	outcome := outcomeSuccess
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		outcome = outcomeCancelled
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		outcome = outcomeTimeout
+	default:
+		outcome = outcomeFailed
+	}
+	commandDuration.WithLabelValues(name, outcome).Observe(time.Since(start).Seconds())
+
+	switch outcome {
+	case outcomeCancelled:
+		return "", fmt.Errorf("command '%v' was cancelled: %w", arg, ctx.Err())
+	case outcomeTimeout:
+		logger.Error("Command '%v' timed out after %s and was killed", arg, b.timeout)
+		return "", &TimeoutError{Command: print, Timeout: b.timeout}
+	case outcomeFailed:
+		return "", fmt.Errorf("error executing command '%v': %v, output: %s", arg, err, output)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,117 @@
+package printers
+
+import (
+	"brother-cube-telegram/config"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/ean"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Supported linear barcode symbologies, see config.ParseBarcodeType
+const (
+	BarcodeCode128 = config.BarcodeCode128
+	BarcodeEAN13   = config.BarcodeEAN13
+	BarcodeCode39  = config.BarcodeCode39
+)
+
+// Width of the narrowest bar in pixels, about 0.28mm at 180 DPI
+const barcodeModuleWidth = 2
+
+// Blank modules required on both sides for scanners to find the code
+const barcodeQuietZoneModules = 10
+
+// Share of the print height used by the human-readable text
+const barcodeTextRatio = 5
+
+// Describes a linear barcode printed on a label
+type Barcode struct {
+	// One of BarcodeCode128, BarcodeEAN13 or BarcodeCode39
+	Type string `json:"type"`
+	// Encoded value, also printed in human-readable form underneath
+	Value string `json:"value"`
+}
+
+// Encodes the barcode value with its symbology
+func (b *Barcode) encode() (barcode.Barcode, error) {
+	var code barcode.Barcode
+	var err error
+
+	switch b.Type {
+	case BarcodeCode128:
+		code, err = code128.Encode(b.Value)
+	case BarcodeEAN13:
+		if len(b.Value) != 12 && len(b.Value) != 13 {
+			return nil, fmt.Errorf("EAN-13 requires 12 or 13 digits, got %d", len(b.Value))
+		}
+		code, err = ean.Encode(b.Value)
+	case BarcodeCode39:
+		code, err = code39.Encode(strings.ToUpper(b.Value), false, true)
+	default:
+		return nil, fmt.Errorf("unsupported barcode type '%s'", b.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s barcode: %v", b.Type, err)
+	}
+	return code, nil
+}
+
+// Renders the barcode with its human-readable value underneath, filling the print height
+// Returns the result encoded as PNG
+func (b *Barcode) render(height int) ([]byte, error) {
+	code, err := b.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	textHeight := max(8, height/barcodeTextRatio)
+	barsHeight := height - textHeight - 2
+	if barsHeight <= 0 {
+		return nil, fmt.Errorf("tape is too narrow for a barcode")
+	}
+
+	quietZone := barcodeQuietZoneModules * barcodeModuleWidth
+	barsWidth := code.Bounds().Dx() * barcodeModuleWidth
+	bars, err := barcode.Scale(code, barsWidth, barsHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scale barcode: %v", err)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, barsWidth+2*quietZone, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(quietZone, 0, quietZone+barsWidth, barsHeight), bars, image.Point{}, draw.Src)
+
+	face, err := newFace(textHeight)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	// Center the human-readable value below the bars
+	text := code.Content()
+	textWidth := font.MeasureString(face, text).Ceil()
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.Black),
+		Face: face,
+		Dot:  fixed.P((img.Bounds().Dx()-textWidth)/2, height-face.Metrics().Descent.Ceil()),
+	}
+	drawer.DrawString(text)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode barcode image: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -5,3 +5,7 @@ const textCmdArg = "--text"
 const fontSizeCmdArg = "--fontsize"
 const fontCmdArg = "--font"
 const writePngCmdArg = "--writepng"
+const imageCmdArg = "--image"
+const cutmarkCmdArg = "--cutmark"
+const padCmdArg = "--pad"
+const chainCmdArg = "--chain"
@@ -4,139 +4,177 @@ import (
 	"brother-cube-telegram/config"
 	"brother-cube-telegram/gpio"
 	"brother-cube-telegram/logger"
+	"brother-cube-telegram/templates"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
+	"strings"
 	"sync"
 	"time"
 )
 
-const print = "ptouch-print"
-
 type Printer struct {
-	config        *config.Config
-	relay         *gpio.Relay
-	shutdownTimer *time.Timer
-	timerMutex    sync.Mutex
+	// Cancelled when the application shuts down, aborts running driver commands
+	ctx     context.Context
+	name    string
+	config  *config.PrinterConfig
+	backend Backend
+	relay   gpio.Switch
+	power   *PowerManager
+
+	// Serializes all access to the printer device
+	deviceMutex sync.Mutex
+	queue       *jobQueue
+	quit        chan struct{}
+	workerDone  chan struct{}
+
+	// Callbacks invoked when a job starts, after each finished job and for monitor alerts
+	startListeners []func(*Job)
+	listeners      []func(*Job)
+	alertListeners []func(Alert)
+	listenersMutex sync.Mutex
+
+	// Closed when the monitor stopped, nil if monitoring is disabled
+	monitorDone chan struct{}
+
+	// Most recent printer info, see LastInfo
+	lastInfo  *PrinterInfo
+	infoMutex sync.Mutex
 }
 
 // Creates a new Printer instance and prints its version and info
 // Can be used to initialize the printer and check its status
 // Takes an optional relay for automatic printer power management
-func NewPrinter(relay *gpio.Relay) *Printer {
-	cfg := config.Get()
-
-	printer := &Printer{
-		config:        cfg,
-		relay:         relay,
-		shutdownTimer: time.NewTimer(cfg.Printer.GetAutoShutdownDelay()),
+// Driver commands are cancelled when ctx is done
+func NewPrinter(ctx context.Context, name string, cfg *config.PrinterConfig, relay gpio.Switch) *Printer {
+	backend, err := newBackend(cfg)
+	if err != nil {
+		logger.Error("Error creating backend for printer '%s': %v", name, err)
+		return nil
 	}
 
-	// Stop the initial timer since we haven't started the printer yet
-	printer.shutdownTimer.Stop()
-
-	// Start the auto-shutdown goroutine if relay is available
-	if relay != nil {
-		go printer.autoShutdownRoutine()
+	printer := &Printer{
+		ctx:        ctx,
+		name:       name,
+		config:     cfg,
+		backend:    backend,
+		relay:      relay,
+		power:      newPowerManager(relay, cfg.GetAutoShutdownDelay()),
+		queue:      newJobQueue(name),
+		quit:       make(chan struct{}),
+		workerDone: make(chan struct{}),
 	}
 
 	// First ensure printer is on, then get version and info
-	if err := printer.ensurePrinterOn(); err != nil {
+	if lease, err := printer.ensurePrinterOn(); err != nil {
 		logger.Warn("Could not ensure printer is on during initialization: %v", err)
+	} else {
+		lease.Release()
 	}
 
 	version := printer.GetVersion()
-	logger.Info("Printer version: %s", version)
+	logger.Info("Printer '%s' version: %s", name, version)
 
 	info, err := printer.GetPrinterInfo()
 	if err != nil {
-		logger.Error("Error getting printer info: %v", err)
+		logger.Error("Error getting info of printer '%s': %v", name, err)
+		// Nothing can close the printer after this, stop its countdown here
+		printer.power.Close()
 		return nil
 	}
 
-	logger.Info("Printer info: %s", info)
-	return printer
-}
+	logger.Info("Printer '%s' info: %s, %dmm %s (%dpx printable)", name, info.Model, info.TapeWidthMM, info.MediaType, info.TapeMaxPixels)
+	if info.HasErrors() {
+		logger.Warn("Printer reports errors: %s", strings.Join(info.Errors.Messages(), ", "))
+	}
 
-// Runs in a goroutine and handles automatic printer shutdown
-func (p *Printer) autoShutdownRoutine() {
-	for {
-		<-p.shutdownTimer.C
-		p.timerMutex.Lock()
-		if p.relay != nil && p.relay.GetState() {
-			logger.Info("Auto-shutdown: Turning off printer after %d minutes of inactivity", p.config.Printer.AutoShutdownDelayMinutes)
-			if err := p.relay.TurnOff(); err != nil {
-				logger.Error("Error during auto-shutdown: %v", err)
-			}
-		}
-		p.timerMutex.Unlock()
+	// Start processing print jobs
+	go printer.worker()
+
+	// Watch the printer for tape and connection problems
+	if cfg.GetMonitorInterval() > 0 {
+		printer.monitorDone = make(chan struct{})
+		go printer.monitor()
 	}
-}
 
-// Resets the auto-shutdown countdown timer
-func (p *Printer) resetAutoShutdownTimer() {
-	if p.relay == nil {
-		return // No auto-shutdown if no relay
+	if relay != nil {
+		relayMetrics.add(name, printer.power)
 	}
 
-	p.timerMutex.Lock()
-	defer p.timerMutex.Unlock()
+	return printer
+}
 
-	// Stop the current timer and reset it
-	if !p.shutdownTimer.Stop() {
-		// If timer already fired, drain the channel
-		select {
-		case <-p.shutdownTimer.C:
-		default:
-		}
-	}
-	p.shutdownTimer.Reset(p.config.Printer.GetAutoShutdownDelay())
+// Returns the configured name of the printer
+func (p *Printer) Name() string {
+	return p.name
+}
+
+// Returns the settings of the printer, e.g. its default font size and layout
+func (p *Printer) Config() *config.PrinterConfig {
+	return p.config
+}
+
+// Returns the power manager, which switches the relay and counts the on-time
+func (p *Printer) Power() *PowerManager {
+	return p.power
 }
 
 // Ensures the printer is powered on via the relay if available
-func (p *Printer) ensurePrinterOn() error {
+// The returned lease keeps the printer on until it is released
+func (p *Printer) ensurePrinterOn() (*PowerLease, error) {
 	if p.relay == nil {
 		// No relay available, assume printer is always on
-		return nil
+		return &PowerLease{}, nil
 	}
 
-	if p.relay.GetState() {
-		// Relay is already on, printer should be powered
-		// Reset the auto-shutdown timer since we're using the printer
-		p.resetAutoShutdownTimer()
-	} else {
-		err := p.relay.TurnOn()
-		if err != nil {
-			return fmt.Errorf("failed to turn on printer via relay: %v", err)
-		}
+	start := time.Now()
+	lease, err := p.power.Acquire()
+	if err != nil {
+		return nil, err
 	}
 
 	// Check via the printer info command if it responds
-	_, err := p.execDirect(infoCmdArg)
+	_, err = p.queryInfo()
+	if lease.poweredOn {
+		defer func() { observePowerOn(p.name, start, err) }()
+	}
 	if err != nil {
 		// Retry with increasing delay
-		for i := range p.config.Printer.RetryAttempts - 1 {
-			time.Sleep(p.config.Printer.GetRetryDelay(i))
-			_, err = p.execDirect(infoCmdArg)
+		for i := range p.config.RetryAttempts - 1 {
+			select {
+			case <-p.ctx.Done():
+				lease.Release()
+				err = fmt.Errorf("cancelled while waiting for the printer: %w", p.ctx.Err())
+				return nil, err
+			case <-time.After(p.config.GetRetryDelay(i)):
+			}
+			_, err = p.queryInfo()
 			if err == nil {
 				break // Successfully powered on
 			}
 		}
 		if err != nil {
 			// If still not responding, return an error
-			return fmt.Errorf("printer did not respond after turning on: %v", err)
+			lease.Release()
+			return nil, fmt.Errorf("printer did not respond after turning on: %w", err)
 		}
 	}
 
-	// Start the auto-shutdown timer since we just turned on the printer
-	p.resetAutoShutdownTimer()
-	return nil
+	return lease, nil
 }
 
 // Returns the printer driver version
 func (p *Printer) GetVersion() string {
-	output, err := p.exec("--version")
+	p.deviceMutex.Lock()
+	defer p.deviceMutex.Unlock()
+
+	lease, err := p.ensurePrinterOn()
+	if err != nil {
+		return "Unknown version"
+	}
+	defer lease.Release()
 
+	output, err := p.backend.Version(p.ctx)
 	if err != nil {
 		return "Unknown version"
 	}
@@ -144,182 +182,375 @@ func (p *Printer) GetVersion() string {
 	return output
 }
 
-// Returns information about the printer
-func (p *Printer) GetPrinterInfo() (string, error) {
-	output, err := p.exec(infoCmdArg)
+// Returns information about the printer and the loaded tape
+// Returns an error if the printer is not powered on
+func (p *Printer) GetPrinterInfo() (*PrinterInfo, error) {
+	p.deviceMutex.Lock()
+	defer p.deviceMutex.Unlock()
 
+	lease, err := p.ensurePrinterOn()
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to ensure printer is on: %w", err)
 	}
+	defer lease.Release()
 
-	return output, nil
+	return p.queryInfo()
 }
 
-func (p *Printer) PrintLabelYolo(label string) error {
-	fontSize := fmt.Sprintf("%d", p.config.Printer.FontSize)
-	output, err := p.exec(fontSizeCmdArg, fontSize, textCmdArg, label)
+// Returns the most recently queried printer info without talking to the printer
+// Returns nil if the printer has not been queried yet
+func (p *Printer) LastInfo() *PrinterInfo {
+	p.infoMutex.Lock()
+	defer p.infoMutex.Unlock()
+	return p.lastInfo
+}
 
+// Queries the backend for printer info and remembers it for LastInfo
+func (p *Printer) queryInfo() (*PrinterInfo, error) {
+	info, err := p.backend.Info(p.ctx)
 	if err != nil {
-		return fmt.Errorf("error printing label: %v, output: %s", err, output)
+		return nil, err
 	}
 
-	logger.Info("Label printed successfully: %s", label)
-	return nil
+	p.infoMutex.Lock()
+	p.lastInfo = info
+	p.infoMutex.Unlock()
+	return info, nil
 }
 
-func (p *Printer) PrintLabel(label string, fontSize int) error {
-	fontSizeStr := fmt.Sprintf("%d", fontSize)
-	output, err := p.exec(fontSizeCmdArg, fontSizeStr, textCmdArg, label)
+// Submits a job to the print queue and returns immediately
+// Use the returned job to wait for or subscribe to its result
+func (p *Printer) Submit(kind JobKind, label Label, meta JobMeta) (*Job, error) {
+	if err := label.Validate(MaxLabelLines); err != nil {
+		return nil, err
+	}
 
+	job, err := p.queue.submit(kind, label, meta)
 	if err != nil {
-		return fmt.Errorf("error printing label: %v, output: %s", err, output)
+		return nil, err
 	}
 
-	logger.Info("Label printed successfully: %s", label)
-	return nil
+	logger.Debug("Job %d queued (%s): %s", job.ID, kind, label.DisplayText())
+	return job, nil
 }
 
-func (p *Printer) PrintLabelWithPreset(label string, preset *config.Preset) error {
-	fontSizeStr := fmt.Sprintf("%d", preset.FontSize)
+// Returns a recently submitted job by ID, or nil if unknown
+func (p *Printer) GetJob(id int64) *Job {
+	return p.queue.get(id)
+}
 
-	var args []string
-	if preset.FontFamily != "" {
-		args = append(args, fontCmdArg, preset.FontFamily)
-	}
-	args = append(args, fontSizeCmdArg, fontSizeStr, textCmdArg, label)
+// Returns the number of unfinished jobs that will run before the given job
+func (p *Printer) JobsAhead(job *Job) int {
+	return p.queue.ahead(job)
+}
 
-	output, err := p.exec(args...)
+// Returns the number of queued and running jobs
+func (p *Printer) PendingJobs() int {
+	return p.queue.pending()
+}
 
-	if err != nil {
-		return fmt.Errorf("error printing label with font: %v, output: %s", err, output)
+// Returns a label for the text using the configured default font size
+// Fails if the text has more lines than configured
+func (p *Printer) DefaultLabel(text string) (Label, error) {
+	label := Label{Text: text, FontSize: int(p.config.FontSize), Layout: LayoutFromConfig(&p.config.Layout)}
+	return label, label.Validate(p.config.MaxLines)
+}
+
+// Returns a label for the text using the font settings of a preset
+// Presets with a barcode type encode the text as that barcode instead
+// Copies, series and layout of the preset are applied to the label
+// Fails if the text has more lines than the preset allows
+func (p *Printer) PresetLabel(text string, preset *config.Preset) (Label, error) {
+	label := Label{Text: text, FontSize: int(preset.FontSize), FontFamily: preset.FontFamily, Copies: preset.Copies}
+	label.Layout = LayoutFromConfig(&p.config.Layout)
+	if preset.Layout != nil {
+		label.Layout = LayoutFromConfig(preset.Layout)
+	}
+	if preset.Barcode != "" {
+		barcodeType, ok := config.ParseBarcodeType(preset.Barcode)
+		if !ok {
+			return Label{}, fmt.Errorf("preset has unsupported barcode type '%s'", preset.Barcode)
+		}
+		label.Text = ""
+		label.Barcode = &Barcode{Type: barcodeType, Value: strings.TrimSpace(text)}
 	}
 
-	logger.Info("Label printed successfully with font '%s': %s", preset.FontFamily, label)
-	return nil
+	if preset.Series != nil {
+		label.Series = &Series{Start: preset.Series.Start, Count: preset.Series.Count, Padding: preset.Series.Padding}
+		if label.Series.Start == 0 {
+			label.Series.Start = 1
+		}
+	}
+
+	return label, label.Validate(preset.MaxLines)
 }
 
-func (p *Printer) PreviewLabel(label string, userIdent int64) ([]byte, error) {
-	draftsFolder := p.config.Printer.DraftsFolder
+// Returns a preset label for data.Text, rendered through the preset's template if it has one
+// data.Preset names the preset and its counter, see history.Store.CounterFunc
+func (p *Printer) TemplatePresetLabel(preset *config.Preset, data templates.Data) (Label, error) {
+	text := data.Text
+	if preset.Template != "" {
+		var err error
+		text, err = templates.Render(data.Preset, preset.Template, p.config.DateFormat, data)
+		if err != nil {
+			return Label{}, err
+		}
+	}
+	return p.PresetLabel(text, preset)
+}
 
-	// Ensure the drafts folder exists
-	if _, err := os.Stat(draftsFolder); os.IsNotExist(err) {
-		if err := os.MkdirAll(draftsFolder, p.config.Printer.GetFolderPermissions()); err != nil {
-			return nil, fmt.Errorf("failed to create drafts folder: %v", err)
+// Processes queued jobs one at a time until the printer is closed
+func (p *Printer) worker() {
+	defer close(p.workerDone)
+
+	for {
+		select {
+		case <-p.quit:
+			return
+		case job := <-p.queue.jobs:
+			// Both can be ready at once, Close fails the jobs still queued
+			select {
+			case <-p.quit:
+				job.finish(nil, ErrPrinterClosed)
+				return
+			default:
+			}
+
+			p.notifyStarted(job)
+			p.runJob(job)
+			recordJobMetrics(job)
+			p.notifyListeners(job)
 		}
-		logger.Info("Created drafts folder: %s", draftsFolder)
 	}
+}
 
-	// Construct the filePath name based on user identifier (e.g. draft-23479234.png)
-	filePath := fmt.Sprintf("%s/draft-%d.png", draftsFolder, userIdent)
+// Registers a callback invoked when the worker picks up a job
+func (p *Printer) OnJobStarted(listener func(*Job)) {
+	p.listenersMutex.Lock()
+	defer p.listenersMutex.Unlock()
+	p.startListeners = append(p.startListeners, listener)
+}
 
-	output, err := p.exec(fontSizeCmdArg, fmt.Sprintf("%d", p.config.Printer.FontSize), textCmdArg, label, writePngCmdArg, filePath)
+// Calls all registered listeners for a started job
+func (p *Printer) notifyStarted(job *Job) {
+	p.listenersMutex.Lock()
+	listeners := p.startListeners
+	p.listenersMutex.Unlock()
 
-	if err != nil {
-		return nil, fmt.Errorf("error previewing label: %v, output: %s", err, output)
+	for _, listener := range listeners {
+		listener(job)
 	}
+}
 
-	fileContent, err := os.ReadFile(filePath)
+// Registers a callback invoked after every finished job, e.g. to record history
+func (p *Printer) OnJobFinished(listener func(*Job)) {
+	p.listenersMutex.Lock()
+	defer p.listenersMutex.Unlock()
+	p.listeners = append(p.listeners, listener)
+}
+
+// Calls all registered listeners for a finished job
+func (p *Printer) notifyListeners(job *Job) {
+	p.listenersMutex.Lock()
+	listeners := p.listeners
+	p.listenersMutex.Unlock()
+
+	for _, listener := range listeners {
+		listener(job)
+	}
+}
+
+// Powers the printer and runs a single job while holding the device
+func (p *Printer) runJob(job *Job) {
+	p.deviceMutex.Lock()
+	defer p.deviceMutex.Unlock()
+
+	job.setState(JobPowering)
+	lease, err := p.ensurePrinterOn()
 	if err != nil {
-		return nil, fmt.Errorf("error reading label preview file: %v", err)
+		logger.Error("Job %d failed: %v", job.ID, err)
+		job.finish(nil, fmt.Errorf("failed to ensure printer is on: %w", err))
+		return
+	}
+	// Keep the printer on until the job is done, the idle countdown starts afterwards
+	defer lease.Release()
+
+	job.setState(JobPrinting)
+
+	var preview []byte
+	labels, err := p.prepareLabels(job.Label.Expand())
+	if err == nil {
+		switch job.Kind {
+		case JobPrint:
+			err = p.printLabels(labels, job.Label.Layout)
+		case JobPreview:
+			preview, err = p.previewLabels(labels, job.Label.Layout, job.ID)
+		default:
+			err = fmt.Errorf("unknown job kind '%s'", job.Kind)
+		}
 	}
 
-	logger.Info("Label previewed successfully: %s", label)
-	return fileContent, nil
+	if err != nil {
+		logger.Error("Job %d failed: %v", job.ID, err)
+	} else {
+		logger.Debug("Job %d done", job.ID)
+	}
+	job.finish(preview, err)
 }
 
-func (p *Printer) PreviewLabelWithPreset(label string, userIdent int64, preset *config.Preset) ([]byte, error) {
-	draftsFolder := p.config.Printer.DraftsFolder
+// Prepares every label of a chain, querying the tape only once
+func (p *Printer) prepareLabels(labels []Label) ([]Label, error) {
+	var info *PrinterInfo
+	prepared := make([]Label, 0, len(labels))
+	for _, label := range labels {
+		if info == nil && label.needsTapeInfo() {
+			var err error
+			info, err = p.queryInfo()
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine print height: %w", err)
+			}
+		}
 
-	// Ensure the drafts folder exists
-	if _, err := os.Stat(draftsFolder); os.IsNotExist(err) {
-		if err := os.MkdirAll(draftsFolder, p.config.Printer.GetFolderPermissions()); err != nil {
-			return nil, fmt.Errorf("failed to create drafts folder: %v", err)
+		label, err := p.prepareLabel(label, info)
+		if err != nil {
+			return nil, err
 		}
-		logger.Info("Created drafts folder: %s", draftsFolder)
+		prepared = append(prepared, label)
 	}
+	return prepared, nil
+}
 
-	// Construct the filePath name based on user identifier (e.g. draft-preset-23479234.png)
-	filePath := fmt.Sprintf("%s/draft-preset-%d.png", draftsFolder, userIdent)
+// Prepares the content of a label and resolves its padding
+// info may be nil if the label does not need the tape information
+func (p *Printer) prepareLabel(label Label, info *PrinterInfo) (Label, error) {
+	label, err := p.prepareContent(label, info)
+	if err != nil {
+		return label, err
+	}
 
-	fontSizeStr := fmt.Sprintf("%d", preset.FontSize)
+	label.padBefore = mmToPx(label.Layout.LeadingMM)
+	label.padAfter = mmToPx(label.Layout.TrailingMM)
+	if label.Layout.MinLengthMM <= 0 {
+		return label, nil
+	}
 
-	var args []string
-	if preset.FontFamily != "" {
-		args = append(args, fontCmdArg, preset.FontFamily)
+	// Measured with the bundled font, which is close to what ptouch-print renders
+	img, err := renderLabel(label, info.TapeMaxPixels)
+	if err != nil {
+		return label, err
+	}
+	if missing := mmToPx(label.Layout.MinLengthMM) - img.Bounds().Dx() - label.padBefore - label.padAfter; missing > 0 {
+		label.padBefore += missing / 2
+		label.padAfter += missing - missing/2
 	}
-	args = append(args, fontSizeCmdArg, fontSizeStr, textCmdArg, label, writePngCmdArg, filePath)
+	return label, nil
+}
 
-	output, err := p.exec(args...)
+// Resolves automatic font sizes, renders QR codes and barcodes and converts images
+// to black and white for the current tape height
+func (p *Printer) prepareContent(label Label, info *PrinterInfo) (Label, error) {
+	lines := len(label.Lines())
+	autoFont := lines > 0 && label.FontSize == AutoFontSize
+	if !autoFont && label.graphicCount() == 0 {
+		return label, nil
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("error previewing label with preset: %v, output: %s", err, output)
+	var err error
+	height := info.TapeMaxPixels
+
+	if autoFont {
+		label.FontSize, err = fitFontSize(lines, height)
+		if err != nil {
+			return label, err
+		}
+		logger.Debug("Auto font size %d for %d line(s) on %dmm tape (%dpx)", label.FontSize, lines, info.TapeWidthMM, height)
 	}
 
-	fileContent, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("error reading label preview file: %v", err)
+	if label.graphicCount() == 0 {
+		return label, nil
 	}
 
-	logger.Info("Label previewed successfully with preset '%s': %s", preset.FontFamily, label)
-	return fileContent, nil
-}
+	if label.QRCode != "" {
+		label.Image, err = renderQRCode(label.QRCode, height)
+		return label, err
+	}
 
-// Shuts down the printer, stopping the auto-shutdown timer, optionally turning off the relay
-func (p *Printer) Close() error {
-	if p.relay == nil {
-		return nil
+	if label.Barcode != nil {
+		label.Image, err = label.Barcode.render(height)
+		return label, err
 	}
 
-	p.timerMutex.Lock()
-	defer p.timerMutex.Unlock()
+	label.Image, err = prepareImage(label.Image, height)
+	return label, err
+}
 
-	// Stop the auto-shutdown timer
-	if p.shutdownTimer != nil {
-		p.shutdownTimer.Stop()
+func (p *Printer) printLabels(labels []Label, layout Layout) error {
+	if err := p.backend.Print(p.ctx, labels, layout); err != nil {
+		return err
 	}
 
-	// Turn off the printer if it's on
-	if p.relay.GetState() {
-		if err := p.relay.TurnOff(); err != nil {
-			logger.Error("Error turning off printer during close: %v", err)
-			return err
-		}
-		logger.Info("Printer turned off during shutdown")
+	label := labels[0]
+	chain := ""
+	if len(labels) > 1 {
+		chain = fmt.Sprintf(" (chain of %d labels)", len(labels))
+	}
+	if text := layout.DisplayText(); text != "" {
+		chain += fmt.Sprintf(" [%s]", text)
 	}
 
+	if label.FontFamily != "" {
+		logger.Info("Label printed successfully with font '%s': %s%s", label.FontFamily, label.DisplayText(), chain)
+	} else {
+		logger.Info("Label printed successfully: %s%s", label.DisplayText(), chain)
+	}
 	return nil
 }
 
-// Executes a command on the printer and returns the output
-// Returns an error if the printer is not powered on
-func (p *Printer) exec(arg ...string) (string, error) {
-	if err := p.ensurePrinterOn(); err != nil {
-		return "", fmt.Errorf("failed to ensure printer is on: %v", err)
+// Renders the labels to a PNG file in the drafts folder and returns its content
+// Every preview gets its own file, the folder is shared by all printers and by the command line
+func (p *Printer) previewLabels(labels []Label, layout Layout, jobID int64) ([]byte, error) {
+	draftsFolder := p.config.DraftsFolder
+
+	// Ensure the drafts folder exists
+	if _, err := os.Stat(draftsFolder); os.IsNotExist(err) {
+		if err := os.MkdirAll(draftsFolder, p.config.GetFolderPermissions()); err != nil {
+			return nil, fmt.Errorf("failed to create drafts folder: %v", err)
+		}
+		logger.Info("Created drafts folder: %s", draftsFolder)
 	}
 
-	// Log the command being executed
-	logger.Debug("Executing command: %s %v", print, arg)
+	// Unique file name starting with the job ID (e.g. draft-42-1234567890.png)
+	file, err := os.CreateTemp(draftsFolder, fmt.Sprintf("draft-%d-*.png", jobID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create label preview file: %v", err)
+	}
+	filePath := file.Name()
+	file.Close()
+	defer os.Remove(filePath)
 
-	command := exec.Command(print, arg...)
-	output, err := command.CombinedOutput()
+	if err := p.backend.RenderPNG(p.ctx, labels, layout, filePath); err != nil {
+		return nil, err
+	}
+
+	fileContent, err := os.ReadFile(filePath)
 	if err != nil {
-		return "", fmt.Errorf("error executing command '%s': %v, output: %s", arg, err, output)
+		return nil, fmt.Errorf("error reading label preview file: %v", err)
 	}
 
-	return string(output), nil
+	logger.Info("Label previewed successfully: %s (%d label(s))", labels[0].DisplayText(), len(labels))
+	return fileContent, nil
 }
 
-// Executes a command on the printer directly without ensuring power-on
-// Used internally to avoid recursion when checking printer status
-func (p *Printer) execDirect(arg ...string) (string, error) {
-	// Log the command being executed
-	logger.Debug("Executing command: %s %v", print, arg)
-
-	command := exec.Command(print, arg...)
-	output, err := command.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("error executing command '%v': %v, output: %s", arg, err, output)
+// Shuts down the printer, stopping the job worker, the monitor and the auto-shutdown countdown, optionally turning off the relay
+func (p *Printer) Close() error {
+	// Let the running job finish, then fail everything still queued
+	close(p.quit)
+	<-p.workerDone
+	if p.monitorDone != nil {
+		<-p.monitorDone
 	}
+	p.queue.close()
 
-	return string(output), nil
+	relayMetrics.remove(p.name, p.power)
+	return p.power.Close()
 }
@@ -0,0 +1,18 @@
+package printers
+
+import (
+	"fmt"
+	"time"
+)
+
+// Returned when the printer driver does not finish in time and was killed
+type TimeoutError struct {
+	// Command that timed out
+	Command string
+	// Deadline the command exceeded
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("printer did not respond within %s, %s was stopped (check the USB connection)", e.Timeout, e.Command)
+}
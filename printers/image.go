@@ -0,0 +1,49 @@
+package printers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Two-color palette of the tape, white background first
+var tapePalette = color.Palette{color.White, color.Black}
+
+// Scales an image (PNG, JPEG, GIF or WebP) to the print height and dithers it to black and white
+// Returns the result encoded as PNG, ready for ptouch-print's image mode
+func prepareImage(data []byte, height int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	bounds := src.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+
+	// Keep the aspect ratio while matching the tape height
+	width := max(1, bounds.Dx()*height/bounds.Dy())
+
+	// Flatten transparent areas (e.g. stickers) onto white paper
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(scaled, scaled.Bounds(), image.White, image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), src, bounds, xdraw.Over, nil)
+
+	dithered := image.NewPaletted(scaled.Bounds(), tapePalette)
+	draw.FloydSteinberg.Draw(dithered, dithered.Bounds(), scaled, image.Point{})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dithered); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,149 @@
+package printers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Error bits reported by the printer status, error information 1 in the low byte, 2 in the high byte
+type ErrorFlags uint16
+
+const (
+	ErrorNoMedia ErrorFlags = 1 << iota
+	ErrorEndOfMedia
+	ErrorCutterJam
+	ErrorWeakBatteries
+	ErrorPrinterInUse
+	_
+	ErrorHighVoltageAdapter
+	_
+	ErrorWrongMedia
+	ErrorExpansionBufferFull
+	ErrorCommunication
+	ErrorCommunicationBufferFull
+	ErrorCoverOpen
+	ErrorOverheating
+	ErrorBlackMarkingNotDetected
+	ErrorSystem
+)
+
+var errorFlagNames = []struct {
+	flag ErrorFlags
+	name string
+}{
+	{ErrorNoMedia, "no tape"},
+	{ErrorEndOfMedia, "end of tape"},
+	{ErrorCutterJam, "cutter jam"},
+	{ErrorWeakBatteries, "weak batteries"},
+	{ErrorPrinterInUse, "printer in use"},
+	{ErrorHighVoltageAdapter, "high-voltage adapter"},
+	{ErrorWrongMedia, "wrong tape"},
+	{ErrorExpansionBufferFull, "expansion buffer full"},
+	{ErrorCommunication, "communication error"},
+	{ErrorCommunicationBufferFull, "communication buffer full"},
+	{ErrorCoverOpen, "cover open"},
+	{ErrorOverheating, "overheating"},
+	{ErrorBlackMarkingNotDetected, "black marking not detected"},
+	{ErrorSystem, "system error"},
+}
+
+// Returns true if the given flag is set
+func (f ErrorFlags) Has(flag ErrorFlags) bool {
+	return f&flag != 0
+}
+
+// Returns a human-readable name for every set flag
+func (f ErrorFlags) Messages() []string {
+	var messages []string
+	for _, entry := range errorFlagNames {
+		if f.Has(entry.flag) {
+			messages = append(messages, entry.name)
+		}
+	}
+	return messages
+}
+
+// Structured printer and tape information
+type PrinterInfo struct {
+	// Printer model, e.g. "PT-P700"
+	Model string `json:"model"`
+	// Maximum printable pixels of the print head
+	PrinterMaxPixels int `json:"printer_max_pixels"`
+	// Maximum printable pixels of the loaded tape, the label height
+	TapeMaxPixels int `json:"tape_max_pixels"`
+	// Width of the loaded tape in millimeters
+	TapeWidthMM int `json:"tape_width_mm"`
+	// Media type, e.g. "Laminated tape"
+	MediaType string `json:"media_type"`
+	TapeColor string `json:"tape_color"`
+	TextColor string `json:"text_color"`
+	// Error bits reported by the printer
+	Errors ErrorFlags `json:"errors"`
+	// Unparsed output of the backend
+	Raw string `json:"-"`
+}
+
+// Returns true if the printer reports any error
+func (i *PrinterInfo) HasErrors() bool {
+	return i.Errors != 0
+}
+
+var (
+	modelPattern        = regexp.MustCompile(`(?m)^(\S+) found on USB bus`)
+	printerWidthPattern = regexp.MustCompile(`for this printer is (\d+)\s*px`)
+	tapeWidthPattern    = regexp.MustCompile(`for this tape is (\d+)\s*px`)
+	mediaTypePattern    = regexp.MustCompile(`media type = [0-9a-fA-F]+ \(([^)]*)\)`)
+	mediaWidthPattern   = regexp.MustCompile(`media width = (\d+)\s*mm`)
+	tapeColorPattern    = regexp.MustCompile(`tape colou?r = [0-9a-fA-F]+ \(([^)]*)\)`)
+	textColorPattern    = regexp.MustCompile(`text colou?r = [0-9a-fA-F]+ \(([^)]*)\)`)
+	errorPattern        = regexp.MustCompile(`error = ([0-9a-fA-F]+)`)
+)
+
+// Parses the output of `ptouch-print --info`
+// Fails if the printable tape width cannot be found, everything else is optional
+func ParsePrinterInfo(output string) (*PrinterInfo, error) {
+	info := &PrinterInfo{Raw: output}
+
+	tapePixels, ok := matchInt(tapeWidthPattern, output)
+	if !ok {
+		return nil, fmt.Errorf("tape width not found in printer info: %s", strings.TrimSpace(output))
+	}
+	info.TapeMaxPixels = tapePixels
+
+	info.Model = matchString(modelPattern, output)
+	info.PrinterMaxPixels, _ = matchInt(printerWidthPattern, output)
+	info.TapeWidthMM, _ = matchInt(mediaWidthPattern, output)
+	info.MediaType = matchString(mediaTypePattern, output)
+	info.TapeColor = matchString(tapeColorPattern, output)
+	info.TextColor = matchString(textColorPattern, output)
+
+	if value := matchString(errorPattern, output); value != "" {
+		flags, err := strconv.ParseUint(value, 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error flags '%s' in printer info", value)
+		}
+		info.Errors = ErrorFlags(flags)
+	}
+
+	return info, nil
+}
+
+// Returns the first submatch of the pattern, or an empty string
+func matchString(pattern *regexp.Regexp, s string) string {
+	match := pattern.FindStringSubmatch(s)
+	if match == nil {
+		return ""
+	}
+	return strings.TrimSpace(match[1])
+}
+
+// Returns the first submatch of the pattern as an integer
+func matchInt(pattern *regexp.Regexp, s string) (int, bool) {
+	value, err := strconv.Atoi(matchString(pattern, s))
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,131 @@
+package printers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePrinterInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected PrinterInfo
+		messages []string
+		err      string
+	}{
+		{
+			name: "loaded tape",
+			output: `PT-P700 found on USB bus 1, device 7
+maximum printing width for this printer is 128px
+maximum printing width for this tape is 76px
+media type = 01 (Laminated tape)
+media width = 12 mm
+tape color = 01 (White)
+text color = 08 (Black)
+error = 0000
+`,
+			expected: PrinterInfo{
+				Model:            "PT-P700",
+				PrinterMaxPixels: 128,
+				TapeMaxPixels:    76,
+				TapeWidthMM:      12,
+				MediaType:        "Laminated tape",
+				TapeColor:        "White",
+				TextColor:        "Black",
+			},
+		},
+		{
+			name: "no tape",
+			output: `PT-P750W found on USB bus 3, device 2
+maximum printing width for this printer is 128px
+maximum printing width for this tape is 0px
+media type = 00 (No media)
+media width = 0 mm
+tape color = 00 (No media)
+text color = 00 (No media)
+error = 0001
+`,
+			expected: PrinterInfo{
+				Model:            "PT-P750W",
+				PrinterMaxPixels: 128,
+				MediaType:        "No media",
+				TapeColor:        "No media",
+				TextColor:        "No media",
+				Errors:           ErrorNoMedia,
+			},
+			messages: []string{"no tape"},
+		},
+		{
+			name: "cover open and cutter jam",
+			output: `PT-2430PC found on USB bus 1, device 4
+maximum printing width for this printer is 128px
+maximum printing width for this tape is 64px
+media type = 03 (Non-laminated tape)
+media width = 9 mm
+tape colour = 02 (Clear)
+text colour = 08 (Black)
+error = 1004
+`,
+			expected: PrinterInfo{
+				Model:            "PT-2430PC",
+				PrinterMaxPixels: 128,
+				TapeMaxPixels:    64,
+				TapeWidthMM:      9,
+				MediaType:        "Non-laminated tape",
+				TapeColor:        "Clear",
+				TextColor:        "Black",
+				Errors:           ErrorCutterJam | ErrorCoverOpen,
+			},
+			messages: []string{"cutter jam", "cover open"},
+		},
+		{
+			name: "unknown model",
+			output: `maximum printing width for this tape is 120px
+media width = 18 mm
+`,
+			expected: PrinterInfo{
+				TapeMaxPixels: 120,
+				TapeWidthMM:   18,
+			},
+		},
+		{
+			name:   "no printer",
+			output: "No P-Touch printer found on USB (remember to put switch to position E)\n",
+			err:    "tape width not found",
+		},
+		{
+			name: "invalid error flags",
+			output: `maximum printing width for this tape is 76px
+error = 10000
+`,
+			err: "invalid error flags",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ParsePrinterInfo(tt.output)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePrinterInfo: %v", err)
+			}
+
+			tt.expected.Raw = tt.output
+			if !reflect.DeepEqual(*info, tt.expected) {
+				t.Errorf("got %+v\nexpected %+v", *info, tt.expected)
+			}
+			if info.HasErrors() != (tt.expected.Errors != 0) {
+				t.Errorf("HasErrors() = %v", info.HasErrors())
+			}
+			if messages := info.Errors.Messages(); !reflect.DeepEqual(messages, tt.messages) {
+				t.Errorf("Messages() = %v, expected %v", messages, tt.messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package printers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Maximum number of stacked lines ptouch-print can put on one label
+const MaxLabelLines = 4
+
+// Font size that is fitted to the loaded tape when the label is printed
+const AutoFontSize = 0
+
+// Describes the content and font settings of a label
+// An image, QR code or barcode, if present, is printed before the text
+type Label struct {
+	// Text printed on the label, newlines or '|' start a new line
+	Text string `json:"text"`
+	// Font size passed to ptouch-print, AutoFontSize to fit the tape
+	FontSize int `json:"font_size"`
+	// Font family/file, empty for the ptouch-print default
+	FontFamily string `json:"font_family,omitempty"`
+	// Image (PNG, JPEG, GIF or WebP), scaled to the tape height when printed
+	Image []byte `json:"image,omitempty"`
+	// Data encoded as a QR code filling the tape height
+	QRCode string `json:"qr_code,omitempty"`
+	// Linear barcode with its human-readable value, filling the tape height
+	Barcode *Barcode `json:"barcode,omitempty"`
+	// Number of copies of each label, 0 or 1 for a single one
+	Copies int `json:"copies,omitempty"`
+	// Prints a numbered series, see SeriesPlaceholder
+	Series *Series `json:"series,omitempty"`
+	// Placement of the labels on the tape
+	Layout Layout `json:"layout"`
+
+	// Blank dots before and after the content, resolved from the layout when the label is prepared
+	padBefore int
+	padAfter  int
+}
+
+// Returns the non-empty lines of the label text
+func (l Label) Lines() []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(l.Text, isLineSeparator) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Returns the label text on a single line, for logs and chat messages
+func (l Label) DisplayText() string {
+	text := strings.Join(l.Lines(), " | ")
+	switch {
+	case l.QRCode != "":
+		text = strings.TrimSpace(fmt.Sprintf("[qr: %s] %s", l.QRCode, text))
+	case l.Barcode != nil:
+		text = strings.TrimSpace(fmt.Sprintf("[%s: %s] %s", l.Barcode.Type, l.Barcode.Value, text))
+	case len(l.Image) > 0:
+		text = strings.TrimSpace("[image] " + text)
+	}
+
+	if l.Series != nil {
+		text += fmt.Sprintf(" (series %d-%d)", l.Series.Start, l.Series.end())
+	}
+	if l.Copies > 1 {
+		text += fmt.Sprintf(" (x%d)", l.Copies)
+	}
+	return text
+}
+
+// Checks the copies and series settings and every label that will be printed
+// Each label needs at least one and at most maxLines lines
+// A maxLines of 0 (or above MaxLabelLines) falls back to MaxLabelLines
+func (l Label) Validate(maxLines int) error {
+	if maxLines <= 0 || maxLines > MaxLabelLines {
+		maxLines = MaxLabelLines
+	}
+
+	if err := l.Layout.Validate(); err != nil {
+		return err
+	}
+
+	// Checked on their own first, their product could overflow
+	if l.Copies < 0 || l.Copies > MaxChainLabels {
+		return fmt.Errorf("number of copies must be between 0 and %d", MaxChainLabels)
+	}
+
+	if l.Series != nil {
+		if err := l.Series.validate(); err != nil {
+			return err
+		}
+	}
+
+	if total := l.Total(); total > MaxChainLabels {
+		return fmt.Errorf("job would print %d labels, but at most %d are allowed", total, MaxChainLabels)
+	}
+
+	for _, label := range l.Expand() {
+		if err := label.validateContent(maxLines); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Checks the content of a single label
+func (l Label) validateContent(maxLines int) error {
+	if l.FontSize < 0 {
+		return fmt.Errorf("font size must not be negative")
+	}
+
+	if l.graphicCount() > 1 {
+		return fmt.Errorf("label can only contain one of image, QR code or barcode")
+	}
+
+	if l.QRCode != "" {
+		if _, err := encodeQRCode(l.QRCode); err != nil {
+			return err
+		}
+	}
+
+	if l.Barcode != nil {
+		if _, err := l.Barcode.encode(); err != nil {
+			return err
+		}
+	}
+
+	lines := l.Lines()
+	if len(lines) == 0 && l.graphicCount() == 0 {
+		return fmt.Errorf("label text is empty")
+	}
+	if len(lines) > maxLines {
+		return fmt.Errorf("label has %d lines, but at most %d are allowed", len(lines), maxLines)
+	}
+
+	// ptouch-print takes every argument starting with a dash as an option and stops printing text there
+	for _, line := range lines {
+		if strings.HasPrefix(line, "-") {
+			return fmt.Errorf("line '%s' starts with '-', which the printer cannot print; use the minus sign '−' or start the line with another character", line)
+		}
+	}
+	return nil
+}
+
+// Returns the number of graphics (image, QR code, barcode) on the label
+func (l Label) graphicCount() int {
+	count := 0
+	if len(l.Image) > 0 {
+		count++
+	}
+	if l.QRCode != "" {
+		count++
+	}
+	if l.Barcode != nil {
+		count++
+	}
+	return count
+}
+
+// Returns true if preparing the label depends on the loaded tape
+func (l Label) needsTapeInfo() bool {
+	return (len(l.Lines()) > 0 && l.FontSize == AutoFontSize) || l.graphicCount() > 0 || l.Layout.needsTapeInfo()
+}
+
+// Builds the ptouch-print arguments describing this label
+// imagePath points to the prepared image file, empty if the label has no image
+// All lines follow a single --text argument so they are stacked on the tape
+func (l Label) args(imagePath string) []string {
+	var args []string
+	if imagePath != "" {
+		args = append(args, imageCmdArg, imagePath)
+	}
+
+	lines := l.Lines()
+	if len(lines) == 0 {
+		return args
+	}
+
+	if l.FontFamily != "" {
+		args = append(args, fontCmdArg, l.FontFamily)
+	}
+	args = append(args, fontSizeCmdArg, fmt.Sprintf("%d", l.FontSize), textCmdArg)
+	args = append(args, lines...)
+	return args
+}
+
+// Returns true for runes that split the label text into lines
+func isLineSeparator(r rune) bool {
+	return r == '\n' || r == '|'
+}
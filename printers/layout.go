@@ -0,0 +1,128 @@
+package printers
+
+import (
+	"brother-cube-telegram/config"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Resolution of the P-touch print head
+const printerDPI = 180
+
+// Where cut marks are printed in a chain of labels
+type CutMarks string
+
+const (
+	// Cut marks between two labels of a chain (default)
+	CutMarksBetween CutMarks = ""
+	// No cut marks, labels of a chain are printed back to back
+	CutMarksNone CutMarks = "none"
+	// Cut marks between labels and before the first and after the last label
+	CutMarksAround CutMarks = "around"
+)
+
+// Parses a cut mark mode, "between" is accepted for the default
+func ParseCutMarks(value string) (CutMarks, bool) {
+	switch CutMarks(value) {
+	case CutMarksBetween, "between":
+		return CutMarksBetween, true
+	case CutMarksNone, CutMarksAround:
+		return CutMarks(value), true
+	}
+	return CutMarksBetween, false
+}
+
+// Returns the cut mark mode as written in the configuration
+func (c CutMarks) String() string {
+	if c == CutMarksBetween {
+		return "between"
+	}
+	return string(c)
+}
+
+// Describes how labels are placed on the tape
+type Layout struct {
+	// Skips the tape feed after the last label so the next job continues right after it
+	Chain bool `json:"chain,omitempty"`
+	// Where cut marks are printed
+	CutMarks CutMarks `json:"cut_marks,omitempty"`
+	// Blank tape before the content of each label in millimeters
+	LeadingMM float64 `json:"leading_mm,omitempty"`
+	// Blank tape after the content of each label in millimeters
+	TrailingMM float64 `json:"trailing_mm,omitempty"`
+	// Minimum length of each label in millimeters, the content is centered on longer labels
+	MinLengthMM float64 `json:"min_length_mm,omitempty"`
+}
+
+// Checks that all lengths are positive and the cut mark mode is known
+func (l Layout) Validate() error {
+	if _, ok := ParseCutMarks(string(l.CutMarks)); !ok {
+		return fmt.Errorf("unknown cut mark mode '%s', expected none, between or around", l.CutMarks)
+	}
+	if l.LeadingMM < 0 || l.TrailingMM < 0 || l.MinLengthMM < 0 {
+		return fmt.Errorf("label padding and length must not be negative")
+	}
+	return nil
+}
+
+// Returns the layout on a single line, empty for the default layout
+func (l Layout) DisplayText() string {
+	var parts []string
+	if l.Chain {
+		parts = append(parts, "chain")
+	}
+	if l.CutMarks != CutMarksBetween {
+		parts = append(parts, fmt.Sprintf("cut marks: %s", l.CutMarks))
+	}
+	if l.LeadingMM > 0 || l.TrailingMM > 0 {
+		parts = append(parts, fmt.Sprintf("padding: %gmm/%gmm", l.LeadingMM, l.TrailingMM))
+	}
+	if l.MinLengthMM > 0 {
+		parts = append(parts, fmt.Sprintf("min length: %gmm", l.MinLengthMM))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Converts a layout from the configuration
+// Unknown cut mark modes are kept so that Validate reports them
+func LayoutFromConfig(cfg *config.LayoutConfig) Layout {
+	cutMarks, ok := ParseCutMarks(cfg.CutMarks)
+	if !ok {
+		cutMarks = CutMarks(cfg.CutMarks)
+	}
+
+	return Layout{
+		Chain:       cfg.Chain,
+		CutMarks:    cutMarks,
+		LeadingMM:   cfg.LeadingMM,
+		TrailingMM:  cfg.TrailingMM,
+		MinLengthMM: cfg.MinLengthMM,
+	}
+}
+
+// Returns true if a cut mark is printed before the i-th of count labels
+func (l Layout) cutMarkBefore(i int) bool {
+	switch l.CutMarks {
+	case CutMarksNone:
+		return false
+	case CutMarksAround:
+		return true
+	}
+	return i > 0
+}
+
+// Returns true if a cut mark is printed after the last label
+func (l Layout) cutMarkAfter() bool {
+	return l.CutMarks == CutMarksAround
+}
+
+// Returns true if resolving the padding depends on the printed size of the label
+func (l Layout) needsTapeInfo() bool {
+	return l.MinLengthMM > 0
+}
+
+// Converts millimeters to dots of the print head
+func mmToPx(mm float64) int {
+	return int(math.Round(mm * printerDPI / 25.4))
+}
@@ -0,0 +1,146 @@
+package printers
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prefix of all metric names
+const metricsNamespace = "brother_cube"
+
+// Outcomes recorded in the metrics
+const (
+	outcomeSuccess   = "success"
+	outcomeFailed    = "failed"
+	outcomeTimeout   = "timeout"
+	outcomeCancelled = "cancelled"
+)
+
+var (
+	labelsPrinted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "labels_printed_total",
+		Help:      "Labels printed, by printer, preset, user and outcome.",
+	}, []string{"printer", "preset", "user", "outcome"})
+
+	commandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "ptouch_command_duration_seconds",
+		Help:      "Execution time of ptouch-print, by command and outcome.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+	}, []string{"command", "outcome"})
+
+	powerOnDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "power_on_duration_seconds",
+		Help:      "Time from switching the relay on until the printer responds, including retries.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 15, 20, 30, 45, 60, 90},
+	}, []string{"printer", "outcome"})
+
+	relayMetrics = newPowerCollector()
+)
+
+func init() {
+	prometheus.MustRegister(relayMetrics)
+}
+
+// Counts the labels of a finished print job
+func recordJobMetrics(job *Job) {
+	if job.Kind != JobPrint {
+		return
+	}
+
+	preset := job.Meta.Preset
+	if preset == "" {
+		preset = "none"
+	}
+	user := job.Meta.Username
+	if user == "" {
+		user = strconv.FormatInt(job.Meta.UserID, 10)
+	}
+	outcome := outcomeSuccess
+	if job.Err() != nil {
+		outcome = outcomeFailed
+	}
+
+	labelsPrinted.WithLabelValues(job.Printer, preset, user, outcome).Add(float64(job.Label.Total()))
+}
+
+// Reports the power state and counters of every printer with a relay when scraped
+type powerCollector struct {
+	mu       sync.Mutex
+	managers map[string]*PowerManager
+
+	on            *prometheus.Desc
+	onTime        *prometheus.Desc
+	powerCycles   *prometheus.Desc
+	autoShutdowns *prometheus.Desc
+}
+
+func newPowerCollector() *powerCollector {
+	labels := []string{"printer"}
+	return &powerCollector{
+		managers: make(map[string]*PowerManager),
+		on: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "printer_powered"),
+			"1 if the printer is switched on, 0 otherwise.", labels, nil),
+		onTime: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "relay_on_seconds_total"),
+			"Time the relay kept the printer powered.", labels, nil),
+		powerCycles: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "relay_power_cycles_total"),
+			"Number of times the relay switched the printer on.", labels, nil),
+		autoShutdowns: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "auto_shutdowns_total"),
+			"Number of times the printer was switched off after being idle.", labels, nil),
+	}
+}
+
+// Adds the power manager of a printer, replacing an earlier one of the same name
+func (c *powerCollector) add(name string, manager *PowerManager) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.managers[name] = manager
+}
+
+// Removes the power manager of a closed printer
+func (c *powerCollector) remove(name string, manager *PowerManager) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.managers[name] == manager {
+		delete(c.managers, name)
+	}
+}
+
+func (c *powerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.on
+	ch <- c.onTime
+	ch <- c.powerCycles
+	ch <- c.autoShutdowns
+}
+
+func (c *powerCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, manager := range c.managers {
+		stats := manager.Stats()
+		on := 0.0
+		if stats.On {
+			on = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.on, prometheus.GaugeValue, on, name)
+		ch <- prometheus.MustNewConstMetric(c.onTime, prometheus.CounterValue, stats.OnTime.Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(c.powerCycles, prometheus.CounterValue, float64(stats.PowerCycles), name)
+		ch <- prometheus.MustNewConstMetric(c.autoShutdowns, prometheus.CounterValue, float64(stats.AutoShutdowns), name)
+	}
+}
+
+// Records the time a printer took to respond after it was switched on
+func observePowerOn(printer string, start time.Time, err error) {
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = outcomeFailed
+	}
+	powerOnDuration.WithLabelValues(printer, outcome).Observe(time.Since(start).Seconds())
+}
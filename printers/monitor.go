@@ -0,0 +1,164 @@
+package printers
+
+import (
+	"brother-cube-telegram/logger"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A problem detected by the printer monitor
+type Incident string
+
+const (
+	IncidentTapeEmpty    Incident = "tape_empty"
+	IncidentCoverOpen    Incident = "cover_open"
+	IncidentWrongTape    Incident = "wrong_tape"
+	IncidentDisconnected Incident = "disconnected"
+	IncidentError        Incident = "error"
+)
+
+// Order in which incidents are checked and reported
+var incidents = []Incident{IncidentDisconnected, IncidentTapeEmpty, IncidentCoverOpen, IncidentWrongTape, IncidentError}
+
+// Number of failed polls in a row before the printer counts as disconnected
+// Tolerates a single failure, e.g. while the printer is still booting after power on
+const disconnectThreshold = 2
+
+// Sent to alert listeners when an incident starts or clears
+type Alert struct {
+	// Name of the affected printer
+	Printer   string
+	Incident  Incident
+	Recovered bool
+	// Human-readable details of the incident
+	Message string
+	Time    time.Time
+}
+
+// Registers a callback invoked when the monitor detects or clears an incident
+func (p *Printer) OnAlert(listener func(Alert)) {
+	p.listenersMutex.Lock()
+	defer p.listenersMutex.Unlock()
+	p.alertListeners = append(p.alertListeners, listener)
+}
+
+// Polls the printer while it is powered and reports incidents until the printer is closed
+func (p *Printer) monitor() {
+	defer close(p.monitorDone)
+
+	ticker := time.NewTicker(p.config.GetMonitorInterval())
+	defer ticker.Stop()
+
+	active := make(map[Incident]string)
+	failures := 0
+
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Only watch a powered printer, a switched off printer is expected to be unreachable
+		// The lease keeps it on during the poll without delaying the auto-shutdown
+		lease := p.power.acquireIfOn()
+		if lease == nil {
+			continue
+		}
+
+		// Skip the poll while a job uses the printer, the job reports its own errors
+		if !p.deviceMutex.TryLock() {
+			lease.Release()
+			continue
+		}
+		info, err := p.queryInfo()
+		p.deviceMutex.Unlock()
+		lease.Release()
+
+		// Failures caused by shutting down are no incidents
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			failures++
+		} else {
+			failures = 0
+		}
+
+		current := p.detectIncidents(info, err, failures)
+
+		// A failed poll says nothing about the tape, keep those incidents until the printer answers again
+		if err != nil {
+			for incident, message := range active {
+				if incident != IncidentDisconnected {
+					current[incident] = message
+				}
+			}
+		}
+
+		for _, incident := range incidents {
+			message, isActive := current[incident]
+			_, wasActive := active[incident]
+
+			switch {
+			case isActive && !wasActive:
+				logger.Warn("Printer '%s' incident: %s", p.name, message)
+				p.notifyAlert(Alert{Printer: p.name, Incident: incident, Message: message, Time: time.Now()})
+			case !isActive && wasActive:
+				logger.Info("Printer '%s' recovered from incident: %s", p.name, active[incident])
+				p.notifyAlert(Alert{Printer: p.name, Incident: incident, Recovered: true, Message: active[incident], Time: time.Now()})
+			}
+		}
+		active = current
+	}
+}
+
+// Returns the incidents found in a poll result with a message for each
+func (p *Printer) detectIncidents(info *PrinterInfo, err error, failures int) map[Incident]string {
+	found := make(map[Incident]string)
+
+	if err != nil {
+		if failures >= disconnectThreshold {
+			found[IncidentDisconnected] = fmt.Sprintf("printer does not respond: %v", err)
+		}
+		return found
+	}
+
+	flags := info.Errors
+	if flags.Has(ErrorNoMedia) || flags.Has(ErrorEndOfMedia) {
+		found[IncidentTapeEmpty] = "tape is empty or missing"
+	}
+	if flags.Has(ErrorCoverOpen) {
+		found[IncidentCoverOpen] = "cover is open"
+	}
+
+	expected := p.config.Monitor.ExpectedTapeWidthMM
+	switch {
+	case flags.Has(ErrorWrongMedia):
+		found[IncidentWrongTape] = "printer reports the wrong tape"
+	case expected > 0 && info.TapeWidthMM > 0 && info.TapeWidthMM != expected:
+		found[IncidentWrongTape] = fmt.Sprintf("%dmm tape loaded, expected %dmm", info.TapeWidthMM, expected)
+	}
+
+	handled := ErrorNoMedia | ErrorEndOfMedia | ErrorCoverOpen | ErrorWrongMedia
+	if other := flags &^ handled; other != 0 {
+		found[IncidentError] = strings.Join(other.Messages(), ", ")
+	}
+
+	return found
+}
+
+// Calls all registered alert listeners
+func (p *Printer) notifyAlert(alert Alert) {
+	p.listenersMutex.Lock()
+	listeners := p.alertListeners
+	p.listenersMutex.Unlock()
+
+	for _, listener := range listeners {
+		listener(alert)
+	}
+}
@@ -0,0 +1,156 @@
+package printers
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/logger"
+	"fmt"
+	"sync"
+)
+
+// Holds all configured printers and decides which one handles a label
+type Pool struct {
+	// Printers in configuration order, nil for printers that failed to start
+	printers []*Printer
+	names    []string
+	routing  config.RoutingConfig
+
+	// Printer chosen per chat with Select, takes precedence over the routing
+	selected      map[int64]string
+	selectedMutex sync.Mutex
+}
+
+// Creates an empty pool using the given routing rules
+func NewPool(routing config.RoutingConfig) *Pool {
+	return &Pool{
+		routing:  routing,
+		selected: make(map[int64]string),
+	}
+}
+
+// Adds a printer under its configured name, printer may be nil if it is not available
+func (p *Pool) Add(name string, printer *Printer) {
+	p.names = append(p.names, name)
+	p.printers = append(p.printers, printer)
+}
+
+// Returns the names of all configured printers
+func (p *Pool) Names() []string {
+	return p.names
+}
+
+// Returns the printer with the given name, or nil if it is unknown or not available
+func (p *Pool) Get(name string) *Printer {
+	for i, n := range p.names {
+		if n == name {
+			return p.printers[i]
+		}
+	}
+	return nil
+}
+
+// Returns the available printers
+func (p *Pool) Available() []*Printer {
+	var available []*Printer
+	for _, printer := range p.printers {
+		if printer != nil {
+			available = append(available, printer)
+		}
+	}
+	return available
+}
+
+// Returns the name of the printer used for a chat
+// A printer selected with Select wins over the routed one, then the default and finally the first printer
+func (p *Pool) NameForChat(chatID int64) string {
+	p.selectedMutex.Lock()
+	selected, ok := p.selected[chatID]
+	p.selectedMutex.Unlock()
+
+	switch {
+	case ok:
+		return selected
+	case p.routing.Chats[chatID] != "":
+		return p.routing.Chats[chatID]
+	}
+	return p.DefaultName()
+}
+
+// Returns the name of the routing's default printer, or of the first printer if none is set
+func (p *Pool) DefaultName() string {
+	switch {
+	case p.routing.Default != "":
+		return p.routing.Default
+	case len(p.names) > 0:
+		return p.names[0]
+	}
+	return ""
+}
+
+// Returns the printer used for a chat, or nil if it is not available
+func (p *Pool) ForChat(chatID int64) *Printer {
+	return p.Get(p.NameForChat(chatID))
+}
+
+// Returns the printer for a preset, which is the preset's printer if it has one and the chat's printer otherwise
+func (p *Pool) ForPreset(chatID int64, preset *config.Preset) *Printer {
+	if preset.Printer != "" {
+		return p.Get(preset.Printer)
+	}
+	return p.ForChat(chatID)
+}
+
+// Switches the printer of a chat until the bot restarts
+func (p *Pool) Select(chatID int64, name string) error {
+	found := false
+	for _, n := range p.names {
+		found = found || n == name
+	}
+	if !found {
+		return fmt.Errorf("unknown printer '%s'", name)
+	}
+
+	p.selectedMutex.Lock()
+	defer p.selectedMutex.Unlock()
+	p.selected[chatID] = name
+	return nil
+}
+
+// Returns the job with the given ID from any printer, or nil if unknown
+func (p *Pool) GetJob(id int64) *Job {
+	for _, printer := range p.Available() {
+		if job := printer.GetJob(id); job != nil {
+			return job
+		}
+	}
+	return nil
+}
+
+// Registers a callback invoked when any printer starts a job
+func (p *Pool) OnJobStarted(listener func(*Job)) {
+	for _, printer := range p.Available() {
+		printer.OnJobStarted(listener)
+	}
+}
+
+// Registers a callback invoked after every finished job of every printer
+func (p *Pool) OnJobFinished(listener func(*Job)) {
+	for _, printer := range p.Available() {
+		printer.OnJobFinished(listener)
+	}
+}
+
+// Registers a callback invoked for monitor alerts of every printer
+func (p *Pool) OnAlert(listener func(Alert)) {
+	for _, printer := range p.Available() {
+		printer.OnAlert(listener)
+	}
+}
+
+// Shuts down all printers
+func (p *Pool) Close() {
+	for _, printer := range p.Available() {
+		if err := printer.Close(); err != nil {
+			logger.Error("Error closing printer '%s': %v", printer.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package printers
+
+import (
+	"brother-cube-telegram/gpio"
+	"brother-cube-telegram/logger"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Returned by power commands for printers without a relay
+var ErrNoPowerSwitch = errors.New("printer has no power switch and is always on")
+
+// Longest time StayOn keeps the printer powered
+const MaxStayOn = 24 * time.Hour
+
+// Switches the printer power for everything that uses the printer
+// Users hold a lease while they need the printer, it is never switched off while a lease is held
+// The idle countdown starts when the last lease is released
+type PowerManager struct {
+	relay     gpio.Switch
+	idleDelay time.Duration
+
+	mu     sync.Mutex
+	leases int
+	// Idle countdown, shutdownAt is zero while it is not running
+	timer      *time.Timer
+	shutdownAt time.Time
+	// Start of the current power-on period, zero while the printer is off
+	onSince time.Time
+	// Lease held by StayOn until holdUntil
+	hold      *PowerLease
+	holdTimer *time.Timer
+	holdUntil time.Time
+
+	onTime        time.Duration
+	powerCycles   int
+	autoShutdowns int
+}
+
+// Snapshot of the power state and counters of a printer
+type PowerStats struct {
+	// False if the printer has no relay and is always on
+	Managed bool
+	On      bool
+	// Number of leases currently held
+	Leases int
+	// Time the printer will be switched off, zero while leases are held or the printer is off
+	ShutdownAt time.Time
+	// End of a StayOn period, zero if there is none
+	HoldUntil time.Time
+	// Total time the printer was powered, including the current period
+	OnTime time.Duration
+	// Number of times the printer was switched on
+	PowerCycles int
+	// Number of times the printer was switched off after being idle
+	AutoShutdowns int
+}
+
+// Keeps the printer powered until it is released
+type PowerLease struct {
+	manager *PowerManager
+	// Restart the idle countdown on release, false for leases that should not count as usage
+	restart bool
+	// True if taking the lease switched the printer on
+	poweredOn bool
+	once      sync.Once
+}
+
+// Creates a power manager for the relay, which may be nil for an always powered printer
+// A printer that is already on starts the idle countdown right away
+func newPowerManager(relay gpio.Switch, idleDelay time.Duration) *PowerManager {
+	m := &PowerManager{relay: relay, idleDelay: idleDelay}
+	if relay != nil && relay.GetState() {
+		m.mu.Lock()
+		m.onSince = time.Now()
+		m.startCountdown(time.Now().Add(idleDelay))
+		m.mu.Unlock()
+	}
+	return m
+}
+
+// Powers the printer if needed and returns a lease keeping it on
+func (m *PowerManager) Acquire() (*PowerLease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.relay == nil {
+		return &PowerLease{}, nil
+	}
+
+	m.stopCountdown()
+	poweredOn := false
+	if !m.relay.GetState() {
+		if err := m.relay.TurnOn(); err != nil {
+			// Let the idle countdown switch off whatever state the relay is in
+			if m.leases == 0 {
+				m.startCountdown(time.Now().Add(m.idleDelay))
+			}
+			return nil, fmt.Errorf("failed to turn on printer via relay: %v", err)
+		}
+		m.onSince = time.Now()
+		m.powerCycles++
+		poweredOn = true
+	} else if m.onSince.IsZero() {
+		// Switched on outside of the manager
+		m.onSince = time.Now()
+	}
+
+	m.leases++
+	return &PowerLease{manager: m, restart: true, poweredOn: poweredOn}, nil
+}
+
+// Returns a lease if the printer is already on, nil otherwise
+// Releasing it does not restart the idle countdown, so background checks do not keep the printer on
+func (m *PowerManager) acquireIfOn() *PowerLease {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.relay == nil {
+		return &PowerLease{}
+	}
+	if !m.relay.GetState() {
+		return nil
+	}
+	if m.onSince.IsZero() {
+		m.onSince = time.Now()
+	}
+
+	m.leases++
+	return &PowerLease{manager: m}
+}
+
+// Returns the lease, starting the idle countdown if it was the last one
+// Releasing a lease more than once has no effect
+func (l *PowerLease) Release() {
+	if l == nil || l.manager == nil {
+		return
+	}
+	l.once.Do(func() { l.manager.release(l.restart) })
+}
+
+// Drops a lease and starts the countdown once none are left
+func (m *PowerManager) release(restart bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.leases--
+	if m.leases > 0 {
+		return
+	}
+
+	shutdownAt := m.shutdownAt
+	if restart || shutdownAt.IsZero() {
+		shutdownAt = time.Now().Add(m.idleDelay)
+	}
+	m.startCountdown(shutdownAt)
+}
+
+// Starts the idle countdown ending at shutdownAt, the caller must hold the mutex
+func (m *PowerManager) startCountdown(shutdownAt time.Time) {
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.shutdownAt = shutdownAt
+	m.timer = time.AfterFunc(time.Until(shutdownAt), m.autoShutdown)
+}
+
+// Stops the idle countdown, the caller must hold the mutex
+func (m *PowerManager) stopCountdown() {
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	m.shutdownAt = time.Time{}
+}
+
+// Switches the printer off when the idle countdown ends without a new lease
+func (m *PowerManager) autoShutdown() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A lease taken or a countdown restarted while this timer fired wins
+	if m.leases > 0 || m.shutdownAt.IsZero() || time.Now().Before(m.shutdownAt) {
+		return
+	}
+	m.stopCountdown()
+
+	if !m.relay.GetState() {
+		m.markOff()
+		return
+	}
+
+	logger.Info("Auto-shutdown: Turning off printer after %s of inactivity", m.idleDelay)
+	if err := m.relay.TurnOff(); err != nil {
+		logger.Error("Error during auto-shutdown: %v", err)
+		return
+	}
+	m.markOff()
+	m.autoShutdowns++
+}
+
+// Adds the current power-on period to the on-time, the caller must hold the mutex
+func (m *PowerManager) markOff() {
+	if !m.onSince.IsZero() {
+		m.onTime += time.Since(m.onSince)
+		m.onSince = time.Time{}
+	}
+}
+
+// Powers the printer and keeps it on for d, replacing an earlier StayOn
+// The idle countdown starts when the period ends
+func (m *PowerManager) StayOn(d time.Duration) error {
+	if m.relay == nil {
+		return ErrNoPowerSwitch
+	}
+	if d <= 0 || d > MaxStayOn {
+		return fmt.Errorf("stay-on time must be positive and at most %.0fh", MaxStayOn.Hours())
+	}
+
+	lease, err := m.Acquire()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	previous := m.hold
+	if m.holdTimer != nil {
+		m.holdTimer.Stop()
+	}
+	m.hold = lease
+	m.holdUntil = time.Now().Add(d)
+	m.holdTimer = time.AfterFunc(d, func() { m.endHold(lease) })
+	m.mu.Unlock()
+
+	// Released after the new lease is taken so the printer stays on
+	previous.Release()
+	logger.Info("Keeping printer on until %s", m.holdUntil.Format("15:04:05"))
+	return nil
+}
+
+// Ends a StayOn period early, the idle countdown starts right away
+// Returns false if there was none
+func (m *PowerManager) CancelStayOn() bool {
+	m.mu.Lock()
+	lease := m.hold
+	if m.holdTimer != nil {
+		m.holdTimer.Stop()
+	}
+	m.clearHold()
+	m.mu.Unlock()
+
+	lease.Release()
+	return lease != nil
+}
+
+// Releases the StayOn lease when its period is over
+func (m *PowerManager) endHold(lease *PowerLease) {
+	m.mu.Lock()
+	if m.hold == lease {
+		m.clearHold()
+	}
+	m.mu.Unlock()
+
+	lease.Release()
+}
+
+// Forgets the StayOn lease, the caller must hold the mutex and release it
+func (m *PowerManager) clearHold() {
+	m.hold = nil
+	m.holdTimer = nil
+	m.holdUntil = time.Time{}
+}
+
+// Switches the printer off right away, ending a StayOn period
+// Fails while a job uses the printer
+func (m *PowerManager) PowerOff() error {
+	if m.relay == nil {
+		return ErrNoPowerSwitch
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The StayOn lease is the only one that may be held, it ends only when the printer goes off
+	busy := m.leases
+	if m.hold != nil {
+		busy--
+	}
+	if busy > 0 {
+		return fmt.Errorf("printer is busy, try again when the current job is done")
+	}
+	if m.hold != nil {
+		hold := m.hold
+		m.holdTimer.Stop()
+		m.clearHold()
+		// Released here as Release would take the mutex
+		hold.once.Do(func() { m.leases-- })
+	}
+	m.stopCountdown()
+
+	if m.relay.GetState() {
+		if err := m.relay.TurnOff(); err != nil {
+			return fmt.Errorf("failed to turn off printer via relay: %v", err)
+		}
+	}
+	m.markOff()
+	return nil
+}
+
+// Returns true if the printer is powered, always true without a relay
+func (m *PowerManager) IsOn() bool {
+	return m.relay == nil || m.relay.GetState()
+}
+
+// Returns the power state and counters
+func (m *PowerManager) Stats() PowerStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := PowerStats{
+		Managed:       m.relay != nil,
+		On:            m.relay == nil || m.relay.GetState(),
+		Leases:        m.leases,
+		ShutdownAt:    m.shutdownAt,
+		HoldUntil:     m.holdUntil,
+		OnTime:        m.onTime,
+		PowerCycles:   m.powerCycles,
+		AutoShutdowns: m.autoShutdowns,
+	}
+	if !m.onSince.IsZero() {
+		stats.OnTime += time.Since(m.onSince)
+	}
+	return stats
+}
+
+// Stops the idle countdown and switches the printer off
+// Must only be called once no job uses the printer anymore
+func (m *PowerManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.relay == nil {
+		return nil
+	}
+	if m.holdTimer != nil {
+		m.holdTimer.Stop()
+	}
+	m.clearHold()
+	m.stopCountdown()
+
+	// Turn off the printer if it's on
+	if m.relay.GetState() {
+		if err := m.relay.TurnOff(); err != nil {
+			logger.Error("Error turning off printer during close: %v", err)
+			return err
+		}
+		logger.Info("Printer turned off during shutdown")
+	}
+	m.markOff()
+	return nil
+}
@@ -0,0 +1,212 @@
+package printers
+
+import (
+	"brother-cube-telegram/gpio"
+	"testing"
+	"time"
+)
+
+// Idle delay of the test power managers, short enough to wait for the auto-shutdown
+const testIdleDelay = 50 * time.Millisecond
+
+// Creates a power manager for a simulated relay that starts off
+func newTestPowerManager(t *testing.T, idleDelay time.Duration) (*PowerManager, *gpio.SimulatedRelay) {
+	t.Helper()
+	relay := gpio.NewSimulatedRelay(17, false)
+	manager := newPowerManager(relay, idleDelay)
+	t.Cleanup(func() { manager.Close() })
+	return manager, relay
+}
+
+func acquire(t *testing.T, manager *PowerManager) *PowerLease {
+	t.Helper()
+	lease, err := manager.Acquire()
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	return lease
+}
+
+// Waits until the relay is off
+func waitForShutdown(t *testing.T, relay *gpio.SimulatedRelay) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for relay.GetState() {
+		if time.Now().After(deadline) {
+			t.Fatal("printer was not switched off")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLeaseKeepsPrinterOn(t *testing.T) {
+	tests := []struct {
+		name   string
+		leases int
+	}{
+		{"one lease", 1},
+		{"several leases", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, relay := newTestPowerManager(t, testIdleDelay)
+
+			var leases []*PowerLease
+			for range tt.leases {
+				leases = append(leases, acquire(t, manager))
+			}
+			if !relay.GetState() {
+				t.Fatal("Acquire did not switch the printer on")
+			}
+
+			// The countdown starts only after the last release
+			for i, lease := range leases {
+				time.Sleep(2 * testIdleDelay)
+				if !relay.GetState() {
+					t.Fatalf("printer switched off while %d lease(s) were held", len(leases)-i)
+				}
+				if stats := manager.Stats(); !stats.ShutdownAt.IsZero() {
+					t.Fatalf("countdown running while %d lease(s) were held", len(leases)-i)
+				}
+				lease.Release()
+				// Releasing twice does not drop another lease
+				lease.Release()
+			}
+
+			if stats := manager.Stats(); stats.Leases != 0 || stats.ShutdownAt.IsZero() {
+				t.Fatalf("after the last release: %d leases, shutdown at %v", stats.Leases, stats.ShutdownAt)
+			}
+			waitForShutdown(t, relay)
+			if stats := manager.Stats(); stats.PowerCycles != 1 || stats.AutoShutdowns != 1 {
+				t.Errorf("%d power cycles, %d auto-shutdowns", stats.PowerCycles, stats.AutoShutdowns)
+			}
+		})
+	}
+}
+
+func TestAcquireIfOn(t *testing.T) {
+	// Long enough that the printer is still on for the background check
+	manager, relay := newTestPowerManager(t, time.Minute)
+	if lease := manager.acquireIfOn(); lease != nil {
+		t.Fatal("acquireIfOn returned a lease for a printer that is off")
+	}
+	if relay.GetState() {
+		t.Fatal("acquireIfOn switched the printer on")
+	}
+
+	acquire(t, manager).Release()
+	shutdownAt := manager.Stats().ShutdownAt
+
+	// A background check does not push the shutdown back
+	lease := manager.acquireIfOn()
+	if lease == nil {
+		t.Fatal("acquireIfOn returned no lease for a printer that is on")
+	}
+	lease.Release()
+	if stats := manager.Stats(); !stats.ShutdownAt.Equal(shutdownAt) {
+		t.Errorf("shutdown moved from %v to %v", shutdownAt, stats.ShutdownAt)
+	}
+}
+
+func TestStayOn(t *testing.T) {
+	manager, relay := newTestPowerManager(t, testIdleDelay)
+
+	if err := manager.StayOn(time.Hour); err != nil {
+		t.Fatalf("StayOn: %v", err)
+	}
+	acquire(t, manager).Release()
+
+	time.Sleep(2 * testIdleDelay)
+	if !relay.GetState() {
+		t.Fatal("printer switched off during StayOn")
+	}
+	if stats := manager.Stats(); !stats.ShutdownAt.IsZero() || stats.HoldUntil.IsZero() {
+		t.Fatalf("during StayOn: shutdown at %v, hold until %v", stats.ShutdownAt, stats.HoldUntil)
+	}
+
+	// Ending it starts the countdown
+	if !manager.CancelStayOn() {
+		t.Fatal("CancelStayOn found no StayOn period")
+	}
+	if manager.CancelStayOn() {
+		t.Fatal("second CancelStayOn found a StayOn period")
+	}
+	waitForShutdown(t, relay)
+}
+
+func TestStayOnLimits(t *testing.T) {
+	manager, relay := newTestPowerManager(t, testIdleDelay)
+	for _, d := range []time.Duration{0, -time.Minute, MaxStayOn + time.Minute} {
+		if err := manager.StayOn(d); err == nil {
+			t.Errorf("StayOn(%v) was accepted", d)
+		}
+	}
+	if relay.GetState() {
+		t.Error("rejected StayOn switched the printer on")
+	}
+
+	if err := newPowerManager(nil, testIdleDelay).StayOn(time.Minute); err != ErrNoPowerSwitch {
+		t.Errorf("StayOn without relay returned %v", err)
+	}
+}
+
+func TestPowerOff(t *testing.T) {
+	tests := []struct {
+		name   string
+		lease  bool
+		stayOn bool
+		busy   bool
+	}{
+		{"idle", false, false, false},
+		{"during StayOn", false, true, false},
+		{"while printing", true, false, true},
+		{"while printing during StayOn", true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, relay := newTestPowerManager(t, testIdleDelay)
+			acquire(t, manager).Release()
+			if tt.stayOn {
+				if err := manager.StayOn(time.Hour); err != nil {
+					t.Fatalf("StayOn: %v", err)
+				}
+			}
+			var lease *PowerLease
+			if tt.lease {
+				lease = acquire(t, manager)
+			}
+
+			err := manager.PowerOff()
+			if tt.busy {
+				if err == nil {
+					t.Fatal("PowerOff succeeded while a lease was held")
+				}
+				if !relay.GetState() {
+					t.Fatal("refused PowerOff switched the printer off")
+				}
+				// The StayOn period is kept
+				if stats := manager.Stats(); stats.HoldUntil.IsZero() != !tt.stayOn {
+					t.Fatalf("refused PowerOff changed StayOn, hold until %v", stats.HoldUntil)
+				}
+				lease.Release()
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("PowerOff: %v", err)
+			}
+			stats := manager.Stats()
+			if relay.GetState() || stats.Leases != 0 || !stats.HoldUntil.IsZero() || !stats.ShutdownAt.IsZero() {
+				t.Fatalf("after PowerOff: on %v, %d leases, hold until %v, shutdown at %v", relay.GetState(), stats.Leases, stats.HoldUntil, stats.ShutdownAt)
+			}
+
+			// The next job powers the printer again
+			acquire(t, manager).Release()
+			if !relay.GetState() {
+				t.Fatal("Acquire after PowerOff did not switch the printer on")
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package printers
+
+import "os/exec"
+
+// Process groups are not available, exec.CommandContext kills the process itself
+func killProcessGroupOnCancel(command *exec.Cmd) {}
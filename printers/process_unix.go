@@ -0,0 +1,17 @@
+//go:build unix
+
+package printers
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Starts the command in its own process group and kills the whole group on cancellation
+// so that helpers spawned by the driver cannot keep the USB device busy
+func killProcessGroupOnCancel(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	command.Cancel = func() error {
+		return syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+	}
+}
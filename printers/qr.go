@@ -0,0 +1,39 @@
+package printers
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+// Encodes data as a QR code with medium error correction
+func encodeQRCode(data string) (barcode.Barcode, error) {
+	code, err := qr.Encode(data, qr.M, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %v", err)
+	}
+	return code, nil
+}
+
+// Renders a square QR code filling the print height, encoded as PNG
+func renderQRCode(data string, height int) ([]byte, error) {
+	code, err := encodeQRCode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Modules are scaled by a whole factor so the code stays sharp on tape
+	scaled, err := barcode.Scale(code, height, height)
+	if err != nil {
+		return nil, fmt.Errorf("QR code does not fit on the tape: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaled); err != nil {
+		return nil, fmt.Errorf("failed to encode QR code image: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,276 @@
+package printers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Size of the buffered job channel feeding the worker
+const jobQueueSize = 32
+
+// Number of finished jobs kept around for lookups by ID
+const maxTrackedJobs = 100
+
+// Returned when a job is submitted to a printer that is shutting down
+var ErrPrinterClosed = errors.New("printer is closed")
+
+// Returned when the job queue is full
+var ErrQueueFull = errors.New("print queue is full")
+
+// Lifecycle state of a job
+type JobState string
+
+const (
+	JobQueued   JobState = "queued"
+	JobPowering JobState = "powering"
+	JobPrinting JobState = "printing"
+	JobDone     JobState = "done"
+	JobFailed   JobState = "failed"
+)
+
+// Returns true if the job will not change state anymore
+func (s JobState) IsFinal() bool {
+	return s == JobDone || s == JobFailed
+}
+
+// What a job does with its label
+type JobKind string
+
+const (
+	JobPrint   JobKind = "print"
+	JobPreview JobKind = "preview"
+)
+
+// Describes who requested a job and how
+type JobMeta struct {
+	// Identifier of the requesting user, 0 for jobs from the API, MQTT and command line
+	UserID   int64
+	ChatID   int64
+	Username string
+	// Name of the preset used, empty for ad-hoc labels
+	Preset string
+	// Value of the preset's counter used by the label, stored once the job is printed, 0 if none
+	Counter uint64
+}
+
+// A single unit of work for the printer worker
+type Job struct {
+	ID        int64
+	Kind      JobKind
+	Label     Label
+	Meta      JobMeta
+	CreatedAt time.Time
+	// Name of the printer the job was submitted to
+	Printer string
+
+	mu          sync.Mutex
+	state       JobState
+	err         error
+	preview     []byte
+	subscribers []chan JobState
+	done        chan struct{}
+}
+
+func newJob(id int64, printer string, kind JobKind, label Label, meta JobMeta) *Job {
+	return &Job{
+		ID:        id,
+		Printer:   printer,
+		Kind:      kind,
+		Label:     label,
+		Meta:      meta,
+		CreatedAt: time.Now(),
+		state:     JobQueued,
+		done:      make(chan struct{}),
+	}
+}
+
+// Returns the current state of the job
+func (j *Job) State() JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+// Returns the error of a failed job, or nil
+func (j *Job) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Returns the rendered PNG of a finished preview job
+func (j *Job) Preview() []byte {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.preview
+}
+
+// Returns a channel that is closed once the job is done or failed
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Blocks until the job has finished or the context is cancelled
+// Returns the job error if it failed
+func (j *Job) Wait(ctx context.Context) error {
+	select {
+	case <-j.done:
+		return j.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Returns a channel receiving every state change of the job, starting with the current one
+// The channel is closed after the final state has been delivered
+func (j *Job) Subscribe() <-chan JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Large enough to hold every state a job can pass through
+	ch := make(chan JobState, 5)
+	ch <- j.state
+	if j.state.IsFinal() {
+		close(ch)
+		return ch
+	}
+
+	j.subscribers = append(j.subscribers, ch)
+	return ch
+}
+
+// Moves the job to a new state and notifies subscribers
+func (j *Job) setState(state JobState) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.state = state
+	for _, ch := range j.subscribers {
+		ch <- state
+	}
+}
+
+// Marks the job as done or failed and releases everyone waiting on it
+func (j *Job) finish(preview []byte, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.preview = preview
+	j.err = err
+	j.state = JobDone
+	if err != nil {
+		j.state = JobFailed
+	}
+
+	for _, ch := range j.subscribers {
+		ch <- j.state
+		close(ch)
+	}
+	j.subscribers = nil
+	close(j.done)
+}
+
+// Holds pending jobs and the jobs that can still be looked up by ID
+type jobQueue struct {
+	printer string
+	jobs    chan *Job
+	mu      sync.Mutex
+	closed  bool
+	tracked map[int64]*Job
+	order   []int64
+}
+
+// Last assigned job ID, shared by all printers so that IDs are unique
+var lastJobID atomic.Int64
+
+func newJobQueue(printer string) *jobQueue {
+	return &jobQueue{
+		printer: printer,
+		jobs:    make(chan *Job, jobQueueSize),
+		tracked: make(map[int64]*Job),
+	}
+}
+
+// Creates a job and places it at the end of the queue
+func (q *jobQueue) submit(kind JobKind, label Label, meta JobMeta) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrPrinterClosed
+	}
+
+	job := newJob(lastJobID.Add(1), q.printer, kind, label, meta)
+
+	select {
+	case q.jobs <- job:
+	default:
+		return nil, ErrQueueFull
+	}
+
+	q.tracked[job.ID] = job
+	q.order = append(q.order, job.ID)
+	if len(q.order) > maxTrackedJobs {
+		delete(q.tracked, q.order[0])
+		q.order = q.order[1:]
+	}
+
+	return job, nil
+}
+
+// Returns a tracked job by ID, or nil if unknown
+func (q *jobQueue) get(id int64) *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.tracked[id]
+}
+
+// Returns the number of unfinished jobs submitted before the given one
+func (q *jobQueue) ahead(job *Job) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	count := 0
+	for _, id := range q.order {
+		if id >= job.ID {
+			break
+		}
+		if !q.tracked[id].State().IsFinal() {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns the number of unfinished jobs
+func (q *jobQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	count := 0
+	for _, job := range q.tracked {
+		if !job.State().IsFinal() {
+			count++
+		}
+	}
+	return count
+}
+
+// Stops accepting jobs and fails everything still waiting in the queue
+func (q *jobQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	for {
+		select {
+		case job := <-q.jobs:
+			job.finish(nil, ErrPrinterClosed)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package printers
+
+import (
+	"brother-cube-telegram/config"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// Creates a printer writing PNG files to a temporary folder
+func newFilePrinter(t *testing.T) (*Printer, string) {
+	t.Helper()
+	folder := t.TempDir()
+	cfg := &config.PrinterConfig{
+		Backend:      config.BackendFile,
+		FileBackend:  config.FileBackendConfig{OutputFolder: folder},
+		DraftsFolder: t.TempDir(),
+	}
+
+	printer := NewPrinter(context.Background(), "test", cfg, nil)
+	if printer == nil {
+		t.Fatal("file backend printer could not be created")
+	}
+	return printer, folder
+}
+
+// Holds the worker at the start of every job until the job is released
+type jobGate struct {
+	release chan struct{}
+	running chan int64
+}
+
+func newJobGate(printer *Printer) *jobGate {
+	gate := &jobGate{release: make(chan struct{}), running: make(chan int64, jobQueueSize)}
+	printer.OnJobStarted(func(job *Job) {
+		gate.running <- job.ID
+		<-gate.release
+	})
+	return gate
+}
+
+// Waits until the worker holds the next job and returns its ID
+func (g *jobGate) next(t *testing.T) int64 {
+	t.Helper()
+	select {
+	case id := <-g.running:
+		return id
+	case <-time.After(5 * time.Second):
+		t.Fatal("no job was started")
+		return 0
+	}
+}
+
+// Submits n print jobs
+func submitJobs(t *testing.T, printer *Printer, n int) []*Job {
+	t.Helper()
+	var jobs []*Job
+	for i := range n {
+		job, err := printer.Submit(JobPrint, Label{Text: fmt.Sprintf("Label %d", i+1)}, JobMeta{Username: "test"})
+		if err != nil {
+			t.Fatalf("Submit of job %d: %v", i+1, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func waitAll(t *testing.T, jobs []*Job) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, job := range jobs {
+		if err := job.Wait(ctx); err != nil {
+			t.Fatalf("job %d failed: %v", job.ID, err)
+		}
+	}
+}
+
+func TestQueueRunsJobsOneAtATimeInOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		jobs int
+	}{
+		{"single job", 1},
+		{"several jobs", 3},
+		{"full queue", jobQueueSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printer, folder := newFilePrinter(t)
+			defer printer.Close()
+			gate := newJobGate(printer)
+
+			jobs := submitJobs(t, printer, tt.jobs)
+
+			for i, job := range jobs {
+				if id := gate.next(t); id != job.ID {
+					t.Fatalf("job %d started as number %d, expected job %d", id, i+1, job.ID)
+				}
+				// Later jobs wait while this one holds the printer
+				for _, later := range jobs[i+1:] {
+					if state := later.State(); state != JobQueued {
+						t.Fatalf("job %d is %s while job %d runs", later.ID, state, job.ID)
+					}
+				}
+				gate.release <- struct{}{}
+			}
+			waitAll(t, jobs)
+
+			files, err := os.ReadDir(folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != tt.jobs {
+				t.Errorf("file backend wrote %d labels, expected %d", len(files), tt.jobs)
+			}
+		})
+	}
+}
+
+func TestQueuePendingJobs(t *testing.T) {
+	tests := []struct {
+		name     string
+		jobs     int
+		finished int
+	}{
+		{"empty", 0, 0},
+		{"one running", 1, 0},
+		{"one running, two queued", 3, 0},
+		{"two done, one running", 3, 2},
+		{"all done", 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printer, _ := newFilePrinter(t)
+			gate := newJobGate(printer)
+			defer func() {
+				close(gate.release)
+				printer.Close()
+			}()
+
+			jobs := submitJobs(t, printer, tt.jobs)
+			for i := range tt.finished {
+				gate.next(t)
+				gate.release <- struct{}{}
+				waitAll(t, jobs[i:i+1])
+			}
+			if tt.finished < tt.jobs {
+				// The next job holds the printer
+				gate.next(t)
+			}
+
+			if pending := printer.PendingJobs(); pending != tt.jobs-tt.finished {
+				t.Errorf("PendingJobs() = %d, expected %d", pending, tt.jobs-tt.finished)
+			}
+			for i, job := range jobs {
+				expected := max(0, i-tt.finished)
+				if ahead := printer.JobsAhead(job); ahead != expected {
+					t.Errorf("JobsAhead(job %d) = %d, expected %d", i+1, ahead, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestQueueCancellation(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, printer *Printer, gate *jobGate, jobs []*Job)
+	}{
+		{"waiting is cancelled, the job still runs", func(t *testing.T, printer *Printer, gate *jobGate, jobs []*Job) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if err := jobs[1].Wait(ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Wait returned %v, expected context.Canceled", err)
+			}
+
+			gate.next(t)
+			gate.release <- struct{}{}
+			gate.next(t)
+			gate.release <- struct{}{}
+			waitAll(t, jobs)
+			printer.Close()
+		}},
+		{"closing fails queued jobs", func(t *testing.T, printer *Printer, gate *jobGate, jobs []*Job) {
+			gate.next(t)
+			closed := make(chan struct{})
+			go func() {
+				printer.Close()
+				close(closed)
+			}()
+
+			// The running job finishes, the queued one is failed
+			<-printer.quit
+			gate.release <- struct{}{}
+			<-closed
+			if err := jobs[0].Err(); err != nil {
+				t.Errorf("running job failed: %v", err)
+			}
+			if err := jobs[1].Err(); !errors.Is(err, ErrPrinterClosed) {
+				t.Errorf("queued job returned %v, expected ErrPrinterClosed", err)
+			}
+			if pending := printer.PendingJobs(); pending != 0 {
+				t.Errorf("PendingJobs() = %d after close", pending)
+			}
+
+			if _, err := printer.Submit(JobPrint, Label{Text: "late"}, JobMeta{}); !errors.Is(err, ErrPrinterClosed) {
+				t.Errorf("Submit after close returned %v, expected ErrPrinterClosed", err)
+			}
+		}},
+	}
+
+	// Every case closes the printer
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printer, _ := newFilePrinter(t)
+			gate := newJobGate(printer)
+			tt.run(t, printer, gate, submitJobs(t, printer, 2))
+		})
+	}
+}
+
+func TestQueueRejectsJobsWhenFull(t *testing.T) {
+	queue := newJobQueue("test")
+	for i := range jobQueueSize {
+		if _, err := queue.submit(JobPrint, Label{Text: "label"}, JobMeta{}); err != nil {
+			t.Fatalf("submit %d: %v", i+1, err)
+		}
+	}
+
+	if _, err := queue.submit(JobPrint, Label{Text: "label"}, JobMeta{}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("submit to a full queue returned %v, expected ErrQueueFull", err)
+	}
+	if pending := queue.pending(); pending != jobQueueSize {
+		t.Errorf("pending() = %d, expected %d", pending, jobQueueSize)
+	}
+}
@@ -0,0 +1,213 @@
+package printers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Blank space in pixels left before and after the text
+const labelMarginPx = 8
+
+var (
+	labelFont     *opentype.Font
+	labelFontErr  error
+	labelFontOnce sync.Once
+)
+
+// Returns a font face for the given pixel size using the bundled Go font
+func newFace(size int) (font.Face, error) {
+	labelFontOnce.Do(func() {
+		labelFont, labelFontErr = opentype.Parse(goregular.TTF)
+	})
+	if labelFontErr != nil {
+		return nil, fmt.Errorf("failed to load label font: %v", labelFontErr)
+	}
+
+	// At 72 DPI one point equals one pixel, matching ptouch-print's font size
+	return opentype.NewFace(labelFont, &opentype.FaceOptions{
+		Size:    float64(size),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
+
+// Smallest font size picked when fitting text to the tape
+const minFontSize = 4
+
+// Returns the largest font size whose line height fits the print height for the given number of lines
+func fitFontSize(lines int, height int) (int, error) {
+	if lines <= 0 {
+		return 0, fmt.Errorf("no lines to fit")
+	}
+	lineHeight := height / lines
+
+	// Line height grows linearly with the size, so start from the measured ratio
+	reference, err := newFace(100)
+	if err != nil {
+		return 0, err
+	}
+	metrics := reference.Metrics()
+	reference.Close()
+
+	size := lineHeight * 100 / max(1, (metrics.Ascent+metrics.Descent).Ceil())
+	for ; size > minFontSize; size-- {
+		face, err := newFace(size)
+		if err != nil {
+			return 0, err
+		}
+		metrics := face.Metrics()
+		face.Close()
+
+		if (metrics.Ascent + metrics.Descent).Ceil() <= lineHeight {
+			break
+		}
+	}
+
+	return max(size, minFontSize), nil
+}
+
+// Renders a label as black on white with the given height in pixels
+// The image comes first, followed by the text with its lines stacked like ptouch-print does
+func renderLabel(label Label, height int) (*image.Gray, error) {
+	var picture image.Image
+	width := 0
+	if len(label.Image) > 0 {
+		decoded, _, err := image.Decode(bytes.NewReader(label.Image))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode label image: %v", err)
+		}
+		picture = decoded
+		width = picture.Bounds().Dx()
+	}
+
+	lines := label.Lines()
+	var face font.Face
+	if len(lines) > 0 {
+		var err error
+		face, err = newFace(label.FontSize)
+		if err != nil {
+			return nil, err
+		}
+		defer face.Close()
+	}
+
+	textLeft := width + labelMarginPx
+	for _, line := range lines {
+		width = max(width, textLeft+font.MeasureString(face, line).Ceil()+labelMarginPx)
+	}
+	if width == 0 {
+		return nil, fmt.Errorf("label is empty")
+	}
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	if picture != nil {
+		draw.Draw(img, picture.Bounds().Sub(picture.Bounds().Min), picture, picture.Bounds().Min, draw.Over)
+	}
+
+	if face == nil {
+		return img, nil
+	}
+
+	// Center the block of lines vertically on the tape
+	metrics := face.Metrics()
+	lineHeight := (metrics.Ascent + metrics.Descent).Ceil()
+	top := (height - lineHeight*len(lines)) / 2
+
+	drawer := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.Black),
+		Face: face,
+	}
+	for i, line := range lines {
+		baseline := top + i*lineHeight + metrics.Ascent.Ceil()
+		drawer.Dot = fixed.P(textLeft, baseline)
+		drawer.DrawString(line)
+	}
+
+	return img, nil
+}
+
+// Width in pixels of the gap holding the cut mark between two chained labels
+const cutMarkGapPx = 9
+
+// Length in pixels of the dashes and gaps of a cut mark
+const cutMarkDashPx = 4
+
+// Renders labels next to each other with their padding and the cut marks of the layout
+func renderChain(labels []Label, layout Layout, height int) (*image.Gray, error) {
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("no labels to render")
+	}
+
+	images := make([]*image.Gray, 0, len(labels))
+	width := 0
+	for i, label := range labels {
+		img, err := renderLabel(label, height)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+		width += label.padBefore + img.Bounds().Dx() + label.padAfter
+		if layout.cutMarkBefore(i) {
+			width += cutMarkGapPx
+		}
+	}
+	if layout.cutMarkAfter() {
+		width += cutMarkGapPx
+	}
+
+	chain := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(chain, chain.Bounds(), image.White, image.Point{}, draw.Src)
+
+	left := 0
+	for i, img := range images {
+		if layout.cutMarkBefore(i) {
+			drawCutMark(chain, left+cutMarkGapPx/2, height)
+			left += cutMarkGapPx
+		}
+		left += labels[i].padBefore
+		draw.Draw(chain, img.Bounds().Add(image.Pt(left, 0)), img, image.Point{}, draw.Src)
+		left += img.Bounds().Dx() + labels[i].padAfter
+	}
+	if layout.cutMarkAfter() {
+		drawCutMark(chain, left+cutMarkGapPx/2, height)
+	}
+
+	return chain, nil
+}
+
+// Draws a dashed vertical line at the given x position
+func drawCutMark(img *image.Gray, x int, height int) {
+	for y := range height {
+		if (y/cutMarkDashPx)%2 == 0 {
+			img.SetGray(x, y, color.Gray{Y: 0})
+		}
+	}
+}
+
+// Encodes an image as PNG into the file at the given path
+func writePNG(img image.Image, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create image file: %v", err)
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, img); err != nil {
+		return fmt.Errorf("failed to encode image: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package printers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Placeholder replaced by the running number of a series
+const SeriesPlaceholder = "{n}"
+
+// Maximum number of labels printed by a single job
+const MaxChainLabels = 100
+
+// Maximum number of digits a series number can be padded to
+const maxSeriesPadding = 10
+
+// Describes a numbered series of labels
+type Series struct {
+	// First number of the series
+	Start int `json:"start"`
+	// Number of labels in the series
+	Count int `json:"count"`
+	// Minimum number of digits, numbers are padded with zeros
+	Padding int `json:"padding,omitempty"`
+}
+
+// Returns the formatted number of the i-th label in the series
+func (s *Series) number(i int) string {
+	return fmt.Sprintf("%0*d", s.Padding, s.Start+i)
+}
+
+// Returns the last number of the series
+func (s *Series) end() int {
+	return s.Start + s.Count - 1
+}
+
+// Checks the series bounds
+func (s *Series) validate() error {
+	if s.Count < 1 {
+		return fmt.Errorf("series needs at least one label")
+	}
+	if s.Count > MaxChainLabels {
+		return fmt.Errorf("series can have at most %d labels", MaxChainLabels)
+	}
+	if s.Start < 0 {
+		return fmt.Errorf("series must not start below 0")
+	}
+	if s.Padding < 0 || s.Padding > maxSeriesPadding {
+		return fmt.Errorf("series padding must be between 0 and %d digits", maxSeriesPadding)
+	}
+	return nil
+}
+
+// Returns the number of labels printed for this label, including copies and series
+// Only meaningful for a validated label, copies and series count are unbounded before
+func (l Label) Total() int {
+	count := max(1, l.Copies)
+	if l.Series != nil {
+		count *= l.Series.Count
+	}
+	return count
+}
+
+// Returns the individual labels to print, in order
+// Series numbers replace the placeholder in the text and codes, each label is repeated per copy
+func (l Label) Expand() []Label {
+	single := l
+	single.Copies = 0
+	single.Series = nil
+
+	var numbered []Label
+	if l.Series == nil {
+		numbered = []Label{single}
+	} else {
+		for i := range l.Series.Count {
+			numbered = append(numbered, single.withNumber(l.Series.number(i)))
+		}
+	}
+
+	labels := make([]Label, 0, l.Total())
+	for _, label := range numbered {
+		for range max(1, l.Copies) {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// Returns a copy of the label with the series placeholder replaced by the number
+// The number is appended to the text if no placeholder is present anywhere
+func (l Label) withNumber(number string) Label {
+	if !l.hasPlaceholder() {
+		l.Text = strings.TrimSpace(l.Text + " " + SeriesPlaceholder)
+	}
+
+	l.Text = strings.ReplaceAll(l.Text, SeriesPlaceholder, number)
+	l.QRCode = strings.ReplaceAll(l.QRCode, SeriesPlaceholder, number)
+	if l.Barcode != nil {
+		barcode := *l.Barcode
+		barcode.Value = strings.ReplaceAll(barcode.Value, SeriesPlaceholder, number)
+		l.Barcode = &barcode
+	}
+	return l
+}
+
+// Returns true if the text or one of the codes contains the series placeholder
+func (l Label) hasPlaceholder() bool {
+	if strings.Contains(l.Text, SeriesPlaceholder) || strings.Contains(l.QRCode, SeriesPlaceholder) {
+		return true
+	}
+	return l.Barcode != nil && strings.Contains(l.Barcode.Value, SeriesPlaceholder)
+}
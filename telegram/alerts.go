@@ -0,0 +1,56 @@
+package telegram
+
+import (
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-telegram/bot"
+)
+
+// Returns an alert listener that sends printer alerts to the chats in TELEGRAM_ADMIN_CHAT_IDS
+// Returns nil if no admin chats are configured
+func AlertNotifier(ctx context.Context, b *bot.Bot) func(printers.Alert) {
+	adminChatIDsStr := os.Getenv("TELEGRAM_ADMIN_CHAT_IDS")
+	if adminChatIDsStr == "" {
+		logger.Info("TELEGRAM_ADMIN_CHAT_IDS environment variable not set - printer alerts are only logged")
+		return nil
+	}
+
+	adminChatIDs := parseChatIDs(adminChatIDsStr, "TELEGRAM_ADMIN_CHAT_IDS")
+
+	return func(alert printers.Alert) {
+		text := formatAlert(alert)
+		for _, chatID := range adminChatIDs {
+			_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatID,
+				Text:   text,
+			})
+			if err != nil {
+				logger.Error("Failed to send printer alert to chat ID %d: %v", chatID, err)
+			}
+		}
+	}
+}
+
+// Formats a monitor alert as a chat message
+func formatAlert(alert printers.Alert) string {
+	if alert.Recovered {
+		return fmt.Sprintf("✅ Printer '%s' recovered at %s, resolved: %s", alert.Printer, alert.Time.Format("15:04"), alert.Message)
+	}
+
+	icon := "⚠️"
+	switch alert.Incident {
+	case printers.IncidentTapeEmpty:
+		icon = "🧻"
+	case printers.IncidentCoverOpen:
+		icon = "🔓"
+	case printers.IncidentWrongTape:
+		icon = "📏"
+	case printers.IncidentDisconnected:
+		icon = "🔌"
+	}
+	return fmt.Sprintf("%s Printer '%s': %s", icon, alert.Printer, alert.Message)
+}
@@ -45,8 +45,8 @@ var commandRegistry = map[string]CommandInfo{
 	},
 	"size": {
 		Command:     "/size",
-		Description: "Print a label with custom font size",
-		Usage:       "/size <font_size> <text>",
+		Description: "Print a label with custom font size, or 'auto' to fit the tape",
+		Usage:       "/size <font_size|auto> <text>",
 		Example:     "/size 32 My Custom Label",
 	},
 	"preset": {
@@ -61,6 +61,66 @@ var commandRegistry = map[string]CommandInfo{
 		Usage:       "/ppreview [preset_name] [text] | /ppreview (to list presets)",
 		Example:     "/ppreview kitchen Container A",
 	},
+	"history": {
+		Command:     "/history",
+		Description: "List your most recently printed labels",
+		Usage:       "/history [count]",
+		Example:     "/history 5",
+	},
+	"reprint": {
+		Command:     "/reprint",
+		Description: "Print a label from your history again with the exact same settings",
+		Usage:       "/reprint <number from /history>",
+		Example:     "/reprint 1",
+	},
+	"qr": {
+		Command:     "/qr",
+		Description: "Print a QR code sized to the tape, with an optional caption beside it",
+		Usage:       "/qr <data> [caption]",
+		Example:     "/qr https://example.com/box/12 Box 12",
+	},
+	"qrpreview": {
+		Command:     "/qrpreview",
+		Description: "Generate a preview image of a QR code label",
+		Usage:       "/qrpreview <data> [caption]",
+		Example:     "/qrpreview https://example.com/box/12 Box 12",
+	},
+	"printer": {
+		Command:     "/printer",
+		Description: "List the printers or switch the printer used by this chat",
+		Usage:       "/printer [name]",
+		Example:     "/printer workshop",
+	},
+	"print": {
+		Command:     "/print",
+		Description: "Print copies or a numbered series of a label as one chain, {n} in the text is replaced by the number. Layout options take millimeters, cut marks are none, between or around",
+		Usage:       "/print [--copies N] [--series START-END] [--digits N] [--chain] [--cutmarks MODE] [--pad MM] [--lead MM] [--trail MM] [--min-length MM] [--preview] <text>",
+		Example:     "/print --series 1-40 --digits 2 Box {n}",
+	},
+	"barcode": {
+		Command:     "/barcode",
+		Description: "Print a linear barcode (code128, ean13 or code39) with its value underneath",
+		Usage:       "/barcode <type> <value>",
+		Example:     "/barcode ean13 4006381333931",
+	},
+	"wake": {
+		Command:     "/wake",
+		Description: "Switch the printer on ahead of a batch so the first label prints without waiting",
+		Usage:       "/wake",
+		Example:     "/wake",
+	},
+	"stayon": {
+		Command:     "/stayon",
+		Description: "Keep the printer on for a while instead of switching it off when idle, a plain number counts minutes",
+		Usage:       "/stayon <duration|off>",
+		Example:     "/stayon 30m",
+	},
+	"off": {
+		Command:     "/off",
+		Description: "Switch the printer off right away",
+		Usage:       "/off",
+		Example:     "/off",
+	},
 }
 
 // GetRegisteredCommands returns all registered commands
@@ -73,9 +133,17 @@ func GetRegisteredCommands() []CommandInfo {
 	// Add the default text message handler info
 	commands = append(commands, CommandInfo{
 		Command:     "Text Message",
-		Description: "Send any text message (not starting with /) to print it with default settings",
+		Description: "Send any text message (not starting with /) to print it with default settings. Use new lines or '|' for stacked lines",
 		Usage:       "Just type your text",
-		Example:     "Hello World",
+		Example:     "Flour | 2026-10",
+	})
+
+	// Add the image message handler info
+	commands = append(commands, CommandInfo{
+		Command:     "Image",
+		Description: "Send a photo, image file or sticker to print it scaled to the tape. The caption is printed beside it",
+		Usage:       "Send an image with an optional caption, start the caption with 'print' or 'preview' to choose what happens",
+		Example:     "print Spices",
 	})
 
 	return commands
@@ -93,6 +161,7 @@ func GetBot(ctx context.Context) *bot.Bot {
 		bot.WithDefaultHandler(defaultHandler),
 		bot.WithMiddlewares(
 			recoveryMiddleware,
+			metricsMiddleware,
 			authorizationMiddleware,
 			createMiddlewareWithCtxFactory(ctx, printerMiddlewareHandler),
 			loggingMiddleware,
@@ -113,6 +182,16 @@ func GetBot(ctx context.Context) *bot.Bot {
 	registerCommandHandler(b, "size", bot.MatchTypeCommandStartOnly, sizeHandler)
 	registerCommandHandler(b, "preset", bot.MatchTypeCommandStartOnly, presetHandler)
 	registerCommandHandler(b, "ppreview", bot.MatchTypeCommandStartOnly, ppreviewHandler)
+	registerCommandHandler(b, "history", bot.MatchTypeCommandStartOnly, historyHandler)
+	registerCommandHandler(b, "reprint", bot.MatchTypeCommandStartOnly, reprintHandler)
+	registerCommandHandler(b, "qr", bot.MatchTypeCommandStartOnly, qrHandler)
+	registerCommandHandler(b, "qrpreview", bot.MatchTypeCommandStartOnly, qrPreviewHandler)
+	registerCommandHandler(b, "barcode", bot.MatchTypeCommandStartOnly, barcodeHandler)
+	registerCommandHandler(b, "print", bot.MatchTypeCommandStartOnly, printHandler)
+	registerCommandHandler(b, "printer", bot.MatchTypeCommandStartOnly, printerHandler)
+	registerCommandHandler(b, "wake", bot.MatchTypeCommandStartOnly, wakeHandler)
+	registerCommandHandler(b, "stayon", bot.MatchTypeCommandStartOnly, stayOnHandler)
+	registerCommandHandler(b, "off", bot.MatchTypeCommandStartOnly, offHandler)
 
 	// Register handler for unknown commands (any command that starts with /)
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/", bot.MatchTypePrefix, unknownCommandHandler)
@@ -0,0 +1,171 @@
+package telegram
+
+import (
+	"brother-cube-telegram/printers"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Layout options given as leading --flags of the /print command
+type printFlags struct {
+	// Send a preview instead of printing
+	preview bool
+	// Number of copies of each label, 0 if not given
+	copies int
+	// Numbered series, nil if not given
+	series *printers.Series
+	// Zero padding of the series numbers, -1 if not given
+	digits int
+	// Skip the tape feed after the last label
+	chain bool
+	// Layout overrides, nil if not given
+	cutMarks    *printers.CutMarks
+	leadingMM   *float64
+	trailingMM  *float64
+	minLengthMM *float64
+}
+
+// Parses the leading --flags of a command argument and returns the remaining text
+// The remaining text is returned untouched so that newlines are kept
+func parsePrintFlags(text string) (printFlags, string, error) {
+	flags := printFlags{digits: -1}
+
+	for {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if !strings.HasPrefix(text, "--") {
+			break
+		}
+
+		var name string
+		name, text = nextToken(text)
+
+		switch name {
+		case "--preview":
+			flags.preview = true
+		case "--copies":
+			var value string
+			value, text = nextToken(text)
+			copies, err := strconv.Atoi(value)
+			if err != nil || copies < 1 || copies > printers.MaxChainLabels {
+				return flags, "", fmt.Errorf("invalid number of copies '%s', expected 1 to %d", value, printers.MaxChainLabels)
+			}
+			flags.copies = copies
+		case "--series":
+			var value string
+			value, text = nextToken(text)
+			series, err := parseSeriesRange(value)
+			if err != nil {
+				return flags, "", err
+			}
+			flags.series = series
+		case "--digits":
+			var value string
+			value, text = nextToken(text)
+			digits, err := strconv.Atoi(value)
+			if err != nil || digits < 0 {
+				return flags, "", fmt.Errorf("invalid number of digits '%s'", value)
+			}
+			flags.digits = digits
+		case "--chain":
+			flags.chain = true
+		case "--cutmarks":
+			var value string
+			value, text = nextToken(text)
+			cutMarks, ok := printers.ParseCutMarks(value)
+			if !ok {
+				return flags, "", fmt.Errorf("invalid cut marks '%s', expected none, between or around", value)
+			}
+			flags.cutMarks = &cutMarks
+		case "--pad", "--lead", "--trail", "--min-length":
+			var value string
+			value, text = nextToken(text)
+			mm, err := strconv.ParseFloat(value, 64)
+			if err != nil || mm < 0 {
+				return flags, "", fmt.Errorf("invalid length '%s' for %s, expected millimeters", value, name)
+			}
+			switch name {
+			case "--pad":
+				flags.leadingMM, flags.trailingMM = &mm, &mm
+			case "--lead":
+				flags.leadingMM = &mm
+			case "--trail":
+				flags.trailingMM = &mm
+			case "--min-length":
+				flags.minLengthMM = &mm
+			}
+		default:
+			return flags, "", fmt.Errorf("unknown option '%s'", name)
+		}
+	}
+
+	if flags.digits >= 0 && flags.series == nil {
+		return flags, "", fmt.Errorf("--digits requires --series")
+	}
+	if flags.series != nil && flags.digits >= 0 {
+		flags.series.Padding = flags.digits
+	}
+
+	return flags, text, nil
+}
+
+// Applies the copies, series and layout options to a label
+func (f printFlags) apply(label *printers.Label) {
+	if f.chain {
+		label.Layout.Chain = true
+	}
+	if f.cutMarks != nil {
+		label.Layout.CutMarks = *f.cutMarks
+	}
+	if f.leadingMM != nil {
+		label.Layout.LeadingMM = *f.leadingMM
+	}
+	if f.trailingMM != nil {
+		label.Layout.TrailingMM = *f.trailingMM
+	}
+	if f.minLengthMM != nil {
+		label.Layout.MinLengthMM = *f.minLengthMM
+	}
+
+	if f.copies > 0 {
+		label.Copies = f.copies
+	}
+	if f.series != nil {
+		series := *f.series
+		label.Series = &series
+	}
+}
+
+// Parses a series range like "1-20", or "20" for 1 to 20
+func parseSeriesRange(value string) (*printers.Series, error) {
+	startText, endText, isRange := strings.Cut(value, "-")
+	if !isRange {
+		startText, endText = "1", value
+	}
+
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid series '%s', expected START-END like 1-20", value)
+	}
+	end, err := strconv.Atoi(endText)
+	if err != nil || end < start {
+		return nil, fmt.Errorf("invalid series '%s', expected START-END like 1-20", value)
+	}
+	// Compared before adding one, end - start + 1 could overflow
+	if end-start >= printers.MaxChainLabels {
+		return nil, fmt.Errorf("series '%s' is too long, at most %d labels are allowed", value, printers.MaxChainLabels)
+	}
+
+	return &printers.Series{Start: start, Count: end - start + 1}, nil
+}
+
+// Splits off the first whitespace separated token of the text
+func nextToken(text string) (string, string) {
+	text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	end := strings.IndexFunc(text, unicode.IsSpace)
+	if end < 0 {
+		return text, ""
+	}
+	return text[:end], text[end:]
+}
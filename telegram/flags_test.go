@@ -0,0 +1,120 @@
+package telegram
+
+import (
+	"brother-cube-telegram/printers"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePrintFlags(t *testing.T) {
+	mm := func(v float64) *float64 { return &v }
+	between := printers.CutMarksBetween
+
+	tests := []struct {
+		name     string
+		input    string
+		expected printFlags
+		text     string
+		err      string
+	}{
+		{
+			name:     "no flags",
+			input:    "Flour\nBest before 2027",
+			expected: printFlags{digits: -1},
+			text:     "Flour\nBest before 2027",
+		},
+		{
+			name:     "copies and preview",
+			input:    "--preview --copies 3 Jam",
+			expected: printFlags{preview: true, copies: 3, digits: -1},
+			text:     "Jam",
+		},
+		{
+			name:     "series with digits",
+			input:    "--series 5-12 --digits 3 Box",
+			expected: printFlags{series: &printers.Series{Start: 5, Count: 8, Padding: 3}, digits: 3},
+			text:     "Box",
+		},
+		{
+			name:     "series end only",
+			input:    "--series 20 Box",
+			expected: printFlags{series: &printers.Series{Start: 1, Count: 20}, digits: -1},
+			text:     "Box",
+		},
+		{
+			name:     "longest series",
+			input:    fmt.Sprintf("--series 1-%d Box", printers.MaxChainLabels),
+			expected: printFlags{series: &printers.Series{Start: 1, Count: printers.MaxChainLabels}, digits: -1},
+			text:     "Box",
+		},
+		{
+			name:     "layout",
+			input:    "--chain --cutmarks between --pad 2 --trail 4.5 --min-length 30 Tag",
+			expected: printFlags{digits: -1, chain: true, cutMarks: &between, leadingMM: mm(2), trailingMM: mm(4.5), minLengthMM: mm(30)},
+			text:     "Tag",
+		},
+		{
+			name:     "flags inside the text are text",
+			input:    "--copies 2 Spices --copies 5\n--chain",
+			expected: printFlags{copies: 2, digits: -1},
+			text:     "Spices --copies 5\n--chain",
+		},
+		{
+			name:     "flag after text",
+			input:    "Box --series 1-3",
+			expected: printFlags{digits: -1},
+			text:     "Box --series 1-3",
+		},
+		{name: "zero copies", input: "--copies 0 Jam", err: "invalid number of copies"},
+		{name: "too many copies", input: fmt.Sprintf("--copies %d Jam", printers.MaxChainLabels+1), err: "invalid number of copies"},
+		{name: "copies not a number", input: "--copies many Jam", err: "invalid number of copies"},
+		{name: "copies without value", input: "--copies", err: "invalid number of copies"},
+		{name: "reversed series", input: "--series 12-5 Box", err: "invalid series"},
+		{name: "series too long", input: fmt.Sprintf("--series 1-%d Box", printers.MaxChainLabels+1), err: "too long"},
+		{name: "series overflowing", input: "--series -9223372036854775807-9223372036854775807 Box", err: "invalid series"},
+		{name: "huge series", input: "--series 0-9223372036854775807 Box", err: "too long"},
+		{name: "negative digits", input: "--series 1-3 --digits -1 Box", err: "invalid number of digits"},
+		{name: "digits without series", input: "--digits 2 Box", err: "--digits requires --series"},
+		{name: "invalid cut marks", input: "--cutmarks everywhere Box", err: "invalid cut marks"},
+		{name: "negative length", input: "--pad -1 Box", err: "invalid length"},
+		{name: "unknown flag", input: "--bold Box", err: "unknown option '--bold'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, text, err := parsePrintFlags(tt.input)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePrintFlags: %v", err)
+			}
+
+			if !reflect.DeepEqual(flags, tt.expected) {
+				t.Errorf("flags %+v, expected %+v", flags, tt.expected)
+			}
+			if text != tt.text {
+				t.Errorf("text %q, expected %q", text, tt.text)
+			}
+		})
+	}
+}
+
+func TestPrintFlagsStayWithinChainLimit(t *testing.T) {
+	flags, _, err := parsePrintFlags(fmt.Sprintf("--copies %d --series 1-%d Box", printers.MaxChainLabels, printers.MaxChainLabels))
+	if err != nil {
+		t.Fatalf("parsePrintFlags: %v", err)
+	}
+
+	// Both are valid alone, the label rejects the combination
+	label := printers.Label{Text: "Box"}
+	flags.apply(&label)
+	if err := label.Validate(printers.MaxLabelLines); err == nil {
+		t.Fatal("label with too many copies of a series was accepted")
+	}
+}
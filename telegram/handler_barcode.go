@@ -0,0 +1,98 @@
+package telegram
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/utils"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func barcodeHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Defer a recovery function to catch any panics
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic in barcodeHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
+
+			// Try to send an error message to the user if possible
+			if update.Message != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "❌ An error occurred while processing your barcode command. Please try again.",
+				})
+			}
+		}
+	}()
+
+	// Check if update has a message and message has text
+	if update.Message == nil {
+		logger.Warn("Received update without message in barcodeHandler")
+		return
+	}
+
+	if update.Message.From == nil {
+		logger.Warn("Received message without sender info in barcodeHandler")
+		return
+	}
+
+	// Parse the command: /barcode <type> <value>
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 3 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessage("barcode"),
+		})
+		return
+	}
+
+	barcodeType, ok := config.ParseBarcodeType(parts[1])
+	if !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text: GetCommandUsageMessageWithError("barcode", fmt.Sprintf("Unsupported barcode type '%s'. Supported types: %s",
+				parts[1], strings.Join(config.BarcodeTypes(), ", "))),
+		})
+		return
+	}
+
+	printer := utils.GetPrinterFromContext(ctx)
+	value := strings.Join(parts[2:], " ")
+	label := printers.Label{
+		FontSize: int(printer.Config().FontSize),
+		Barcode:  &printers.Barcode{Type: barcodeType, Value: value},
+		Layout:   printers.LayoutFromConfig(&printer.Config().Layout),
+	}
+	if err := label.Validate(printers.MaxLabelLines); err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessageWithError("barcode", fmt.Sprintf("Invalid barcode: %s.", err.Error())),
+		})
+		return
+	}
+
+	logger.Info("Barcode %s requested: %s from %s", barcodeType, value, update.Message.From.Username)
+
+	_, err := runPrinterJob(ctx, b, update.Message.Chat.ID, printer, printers.JobPrint, label, jobMetaFromMessage(update.Message, ""))
+	if err != nil {
+		logger.Error("Error printing barcode: %v", err)
+
+		// Inform the user about the error
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Failed to print barcode: " + err.Error(),
+		})
+		return
+	}
+
+	// Send success message
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   fmt.Sprintf("✅ %s barcode printed successfully!", barcodeType),
+	})
+}
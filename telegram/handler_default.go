@@ -2,6 +2,7 @@ package telegram
 
 import (
 	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
 	"brother-cube-telegram/utils"
 	"context"
 	"strings"
@@ -15,6 +16,7 @@ func defaultHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Error("Recovered from panic in defaultHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
 
 			// Try to send an error message to the user if possible
 			if update.Message != nil {
@@ -32,8 +34,14 @@ func defaultHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 		return
 	}
 
+	if update.Message.From == nil {
+		logger.Warn("Received message without sender info")
+		return
+	}
+
 	if update.Message.Text == "" {
-		logger.Debug("Received message without text (possibly media)")
+		// Photos, image documents and stickers are printed as images
+		imageHandler(ctx, b, update)
 		return
 	}
 
@@ -43,17 +51,15 @@ func defaultHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 		return
 	}
 
-	if update.Message.From == nil {
-		logger.Warn("Received message without sender info")
-		return
-	}
-
 	logger.Info("Received message: %s from %s", update.Message.Text, update.Message.From.Username)
 
 	printer := utils.GetPrinterFromContext(ctx)
 
 	// Wrap the printer call in error handling
-	err := printer.PrintLabelYolo(update.Message.Text)
+	label, err := printer.DefaultLabel(update.Message.Text)
+	if err == nil {
+		_, err = runPrinterJob(ctx, b, update.Message.Chat.ID, printer, printers.JobPrint, label, jobMetaFromMessage(update.Message, ""))
+	}
 	if err != nil {
 		logger.Error("Error printing label: %v", err)
 
@@ -15,6 +15,7 @@ func helpHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Error("Recovered from panic in helpHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
 
 			// Try to send an error message to the user if possible
 			if update.Message != nil {
@@ -80,6 +81,7 @@ func helpHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	message.WriteString("📝 Tips:\n")
 	message.WriteString("• Use /preset without arguments to see available presets\n")
 	message.WriteString("• Preview your labels before printing to save tape\n")
+	message.WriteString("• Put text on separate lines or use '|' to stack lines on one label\n")
 	message.WriteString("• The bot will automatically manage printer power\n")
 	message.WriteString("• Use '/help <command>' for detailed help on a specific command\n\n")
 
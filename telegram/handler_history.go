@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"brother-cube-telegram/config"
+	"brother-cube-telegram/history"
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/utils"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// Upper bound for the number of entries listed at once
+const maxHistoryEntries = 50
+
+// Fallback when no default count is configured
+const defaultHistoryEntries = 10
+
+func historyHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Defer a recovery function to catch any panics
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic in historyHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
+
+			// Try to send an error message to the user if possible
+			if update.Message != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "❌ An error occurred while processing your history request. Please try again.",
+				})
+			}
+		}
+	}()
+
+	// Check if update has a message and message has text
+	if update.Message == nil {
+		logger.Warn("Received update without message in historyHandler")
+		return
+	}
+
+	if update.Message.From == nil {
+		logger.Warn("Received message without sender info in historyHandler")
+		return
+	}
+
+	store := utils.GetHistoryFromContext(ctx)
+	if store == nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Print history is not available.",
+		})
+		return
+	}
+
+	// Parse the command: /history [count]
+	count := config.Get().History.DefaultEntries
+	if count <= 0 {
+		count = defaultHistoryEntries
+	}
+
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) > 1 {
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   GetCommandUsageMessageWithError("history", fmt.Sprintf("Invalid count '%s'. Please provide a positive number.", parts[1])),
+			})
+			return
+		}
+		count = min(n, maxHistoryEntries)
+	}
+
+	logger.Info("History (%d entries) requested by %s", count, update.Message.From.Username)
+
+	entries, err := store.Recent(update.Message.From.ID, count)
+	if err != nil {
+		logger.Error("Error reading print history: %v", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Failed to read print history: " + err.Error(),
+		})
+		return
+	}
+
+	if len(entries) == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "📭 You have not printed any labels yet.",
+		})
+		return
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("🕘 Your last %d label(s):\n\n", len(entries)))
+
+	for i, entry := range entries {
+		message.WriteString(formatHistoryEntry(i+1, &entry))
+	}
+
+	message.WriteString("\n💡 Use /reprint <number> to print a label again")
+
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   message.String(),
+	})
+
+	if err != nil {
+		logger.Error("Failed to send history message: %v", err)
+	}
+}
+
+// Formats a single numbered history line
+func formatHistoryEntry(number int, entry *history.Entry) string {
+	icon := "✅"
+	if entry.Outcome == history.OutcomeFailed {
+		icon = "❌"
+	}
+
+	settings := fmt.Sprintf("size %s", config.FontSize(entry.Label.FontSize))
+	if entry.Preset != "" {
+		settings = fmt.Sprintf("preset %s", entry.Preset)
+	}
+
+	line := fmt.Sprintf("%d. %s %s \"%s\" (%s)\n", number, icon, entry.Timestamp.Format("02.01.2006 15:04"), entry.DisplayText(), settings)
+	if entry.Error != "" {
+		line += fmt.Sprintf("    ⚠️ %s\n", entry.Error)
+	}
+	if !entry.Reprintable() {
+		line += "    🖼 Image too large to keep, cannot be reprinted\n"
+	}
+	return line
+}
@@ -0,0 +1,173 @@
+package telegram
+
+import (
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/utils"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// Largest file the Telegram bot API allows to download
+const maxImageDownloadBytes = 20 << 20
+
+// Smallest photo height worth downloading, taller than any tape
+const minPhotoHeight = 256
+
+// Caption keywords selecting what happens with an image
+const (
+	imagePreviewKeyword = "preview"
+	imagePrintKeyword   = "print"
+)
+
+// Handles photos, image documents and stickers sent to the bot
+// The caption is printed beside the image. A leading "preview" or "print" keyword
+// overrides the configured image_preview_first behavior
+func imageHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	message := update.Message
+
+	fileID, ok := imageFileID(message)
+	if !ok {
+		logger.Debug("Received message without text or printable image")
+		return
+	}
+
+	printer := utils.GetPrinterFromContext(ctx)
+	cfg := printer.Config()
+	preview, caption := parseImageCaption(message.Caption, cfg.ImagePreviewFirst)
+
+	logger.Info("Received image from %s (preview: %t, caption: %s)", message.From.Username, preview, caption)
+
+	data, err := downloadTelegramFile(ctx, b, fileID)
+	if err != nil {
+		logger.Error("Error downloading image: %v", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   "❌ Failed to download image: " + err.Error(),
+		})
+		return
+	}
+
+	label := printers.Label{Text: caption, FontSize: int(cfg.FontSize), Image: data, Layout: printers.LayoutFromConfig(&cfg.Layout)}
+	if err := label.Validate(cfg.MaxLines); err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   "❌ Invalid label: " + err.Error(),
+		})
+		return
+	}
+
+	if preview {
+		job, err := runPrinterJob(ctx, b, message.Chat.ID, printer, printers.JobPreview, label, jobMetaFromMessage(message, ""))
+		if err != nil {
+			logger.Error("Error generating image preview: %v", err)
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: message.Chat.ID,
+				Text:   "❌ Failed to generate image preview: " + err.Error(),
+			})
+			return
+		}
+
+		b.SendPhoto(ctx, &bot.SendPhotoParams{
+			ChatID:  message.Chat.ID,
+			Photo:   &models.InputFileUpload{Filename: "image-preview.png", Data: bytes.NewReader(job.Preview())},
+			Caption: fmt.Sprintf("Preview of your image label\n💡 Send it again with the caption '%s' to print it", strings.TrimSpace(imagePrintKeyword+" "+caption)),
+		})
+		return
+	}
+
+	_, err = runPrinterJob(ctx, b, message.Chat.ID, printer, printers.JobPrint, label, jobMetaFromMessage(message, ""))
+	if err != nil {
+		logger.Error("Error printing image: %v", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: message.Chat.ID,
+			Text:   "❌ Failed to print image: " + err.Error(),
+		})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: message.Chat.ID,
+		Text:   "✅ Image printed successfully!",
+	})
+}
+
+// Returns the ID of the file to print for photos, image documents and stickers
+func imageFileID(message *models.Message) (string, bool) {
+	switch {
+	case len(message.Photo) > 0:
+		// Sizes are sorted ascending, take the smallest one that is still sharp on tape
+		for _, size := range message.Photo {
+			if size.Height >= minPhotoHeight {
+				return size.FileID, true
+			}
+		}
+		return message.Photo[len(message.Photo)-1].FileID, true
+
+	case message.Document != nil && strings.HasPrefix(message.Document.MimeType, "image/"):
+		return message.Document.FileID, true
+
+	case message.Sticker != nil:
+		// Animated and video stickers cannot be decoded, fall back to their thumbnail
+		if message.Sticker.IsAnimated || message.Sticker.IsVideo {
+			if message.Sticker.Thumbnail == nil {
+				return "", false
+			}
+			return message.Sticker.Thumbnail.FileID, true
+		}
+		return message.Sticker.FileID, true
+	}
+
+	return "", false
+}
+
+// Splits an optional leading keyword from the caption
+// Returns whether a preview was requested and the remaining caption text
+func parseImageCaption(caption string, previewFirst bool) (bool, string) {
+	caption = strings.TrimSpace(caption)
+	keyword, rest, _ := strings.Cut(caption, " ")
+
+	switch strings.ToLower(keyword) {
+	case imagePreviewKeyword:
+		return true, strings.TrimSpace(rest)
+	case imagePrintKeyword:
+		return false, strings.TrimSpace(rest)
+	}
+	return previewFirst, caption
+}
+
+// Downloads a file sent to the bot
+func downloadTelegramFile(ctx context.Context, b *bot.Bot, fileID string) ([]byte, error) {
+	file, err := b.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %v", err)
+	}
+
+	if file.FileSize > maxImageDownloadBytes {
+		return nil, fmt.Errorf("file is too large (%d bytes)", file.FileSize)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.FileDownloadLink(file), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download file: %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxImageDownloadBytes))
+}
@@ -0,0 +1,216 @@
+package telegram
+
+import (
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func wakeHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Defer a recovery function to catch any panics
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic in wakeHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
+
+			// Try to send an error message to the user if possible
+			if update.Message != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "❌ An error occurred while waking the printer. Please try again.",
+				})
+			}
+		}
+	}()
+
+	if update.Message == nil {
+		logger.Warn("Received update without message in wakeHandler")
+		return
+	}
+
+	printer := utils.GetPrinterFromContext(ctx)
+	if !printer.Power().Stats().Managed {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "ℹ️ This printer has no power switch and is always on.",
+		})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   "⏳ Switching the printer on...",
+	})
+
+	// Querying the info waits until the printer answers after powering up
+	info, err := printer.GetPrinterInfo()
+	if err != nil {
+		logger.Error("Error waking printer: %v", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Failed to wake the printer: " + err.Error(),
+		})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   fmt.Sprintf("✅ Printer is ready with %dmm %s tape.\n%s", info.TapeWidthMM, utils.ValueOrUnknown(info.MediaType), formatPowerStats(printer.Power().Stats())),
+	})
+}
+
+func stayOnHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Defer a recovery function to catch any panics
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic in stayOnHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
+
+			// Try to send an error message to the user if possible
+			if update.Message != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "❌ An error occurred while processing your stayon command. Please try again.",
+				})
+			}
+		}
+	}()
+
+	if update.Message == nil {
+		logger.Warn("Received update without message in stayOnHandler")
+		return
+	}
+
+	printer := utils.GetPrinterFromContext(ctx)
+
+	// Parse the command: /stayon <duration|off>
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessage("stayon"),
+		})
+		return
+	}
+
+	if strings.EqualFold(parts[1], "off") {
+		message := "ℹ️ The printer was not kept on."
+		if printer.Power().CancelStayOn() {
+			message = "✅ Stopped keeping the printer on."
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   message + "\n" + formatPowerStats(printer.Power().Stats()),
+		})
+		return
+	}
+
+	duration, err := parseStayOnDuration(parts[1])
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessageWithError("stayon", err.Error()),
+		})
+		return
+	}
+
+	if err := printer.Power().StayOn(duration); err != nil {
+		logger.Error("Error keeping printer on: %v", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Failed to keep the printer on: " + err.Error(),
+		})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   "✅ " + formatPowerStats(printer.Power().Stats()),
+	})
+}
+
+func offHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Defer a recovery function to catch any panics
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic in offHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
+
+			// Try to send an error message to the user if possible
+			if update.Message != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "❌ An error occurred while switching the printer off. Please try again.",
+				})
+			}
+		}
+	}()
+
+	if update.Message == nil {
+		logger.Warn("Received update without message in offHandler")
+		return
+	}
+
+	printer := utils.GetPrinterFromContext(ctx)
+	if err := printer.Power().PowerOff(); err != nil {
+		if !errors.Is(err, printers.ErrNoPowerSwitch) {
+			logger.Error("Error switching printer off: %v", err)
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Cannot switch the printer off: " + err.Error(),
+		})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   "✅ Printer switched off.",
+	})
+}
+
+// Parses a stay-on time like "30m" or "2h", a plain number counts minutes
+func parseStayOnDuration(value string) (time.Duration, error) {
+	if minutes, err := strconv.Atoi(value); err == nil {
+		value = fmt.Sprintf("%dm", minutes)
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s', use e.g. 30m or 2h", value)
+	}
+	if duration <= 0 || duration > printers.MaxStayOn {
+		return 0, fmt.Errorf("duration must be positive and at most %.0fh", printers.MaxStayOn.Hours())
+	}
+	return duration, nil
+}
+
+// Formats the power state with the remaining idle time
+func formatPowerStats(stats printers.PowerStats) string {
+	if !stats.Managed {
+		return "🔌 Power: always on (no power switch)"
+	}
+	if !stats.On {
+		return "🔌 Power: off, use /wake to switch it on"
+	}
+
+	switch {
+	case !stats.HoldUntil.IsZero():
+		return fmt.Sprintf("🔌 Power: on, kept on until %s", stats.HoldUntil.Format("15:04"))
+	case stats.Leases > 0:
+		return "🔌 Power: on, in use"
+	case !stats.ShutdownAt.IsZero():
+		return fmt.Sprintf("🔌 Power: on, switches off in %s when idle", time.Until(stats.ShutdownAt).Round(time.Second))
+	default:
+		return "🔌 Power: on"
+	}
+}
@@ -3,6 +3,7 @@ package telegram
 import (
 	"brother-cube-telegram/config"
 	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
 	"brother-cube-telegram/utils"
 	"bytes"
 	"context"
@@ -18,6 +19,7 @@ func ppreviewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Error("Recovered from panic in ppreviewHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
 
 			// Try to send an error message to the user if possible
 			if update.Message != nil {
@@ -65,9 +67,8 @@ func ppreviewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	presetName := parts[1]
 	textToPreview := parts[2]
 
-	// Get the configuration and printer
+	// Get the configuration
 	cfg := config.Get()
-	printer := utils.GetPrinterFromContext(ctx)
 
 	// Look up the preset
 	preset := cfg.Printer.GetPreset(presetName)
@@ -76,11 +77,31 @@ func ppreviewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 		return
 	}
 
-	logger.Info("Using preset '%s' (font size: %d, font family: %s) to preview: %s from %s",
+	// Presets can be routed to their own printer
+	printer, err := presetPrinter(ctx, update.Message.Chat.ID, preset)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("❌ Cannot use preset '%s': %s", presetName, err.Error()),
+		})
+		return
+	}
+
+	logger.Info("Using preset '%s' (font size: %s, font family: %s) to preview: %s from %s",
 		presetName, preset.FontSize, preset.FontFamily, textToPreview, update.Message.From.Username)
 
-	// Generate preview with the preset's font size and family
-	img, err := printer.PreviewLabelWithPreset(textToPreview, update.Message.From.ID, preset)
+	// Generate preview with the preset's template, font size and family
+	// Previews show the next counter value without reserving it
+	counter := utils.GetHistoryFromContext(ctx).ReserveCounter(presetName, true)
+	text, err := renderPresetText(update.Message, presetName, preset, textToPreview, counter)
+	var label printers.Label
+	if err == nil {
+		label, err = printer.PresetLabel(text, preset)
+	}
+	var job *printers.Job
+	if err == nil {
+		job, err = runPrinterJob(ctx, b, update.Message.Chat.ID, printer, printers.JobPreview, label, jobMetaFromMessage(update.Message, presetName))
+	}
 	if err != nil {
 		logger.Error("Error generating preset preview for '%s': %v", presetName, err)
 
@@ -93,16 +114,19 @@ func ppreviewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	}
 
 	// Send the preview image
-	fontInfo := fmt.Sprintf("📏 Font size: %d", preset.FontSize)
+	fontInfo := fmt.Sprintf("📏 Font size: %s", preset.FontSize)
 	if preset.FontFamily != "" {
 		fontInfo += fmt.Sprintf(", Font: %s", preset.FontFamily)
 	}
 
 	caption := fmt.Sprintf("Preview using preset '%s'\n%s", presetName, fontInfo)
+	if preset.Template != "" {
+		caption += fmt.Sprintf("\n📝 %s", label.DisplayText())
+	}
 
 	b.SendPhoto(ctx, &bot.SendPhotoParams{
 		ChatID:  update.Message.Chat.ID,
-		Photo:   &models.InputFileUpload{Filename: "preset-preview.png", Data: bytes.NewReader(img)},
+		Photo:   &models.InputFileUpload{Filename: "preset-preview.png", Data: bytes.NewReader(job.Preview())},
 		Caption: caption,
 	})
 }
@@ -2,7 +2,10 @@ package telegram
 
 import (
 	"brother-cube-telegram/config"
+	"brother-cube-telegram/history"
 	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/templates"
 	"brother-cube-telegram/utils"
 	"context"
 	"fmt"
@@ -17,6 +20,7 @@ func presetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Error("Recovered from panic in presetHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
 
 			// Try to send an error message to the user if possible
 			if update.Message != nil {
@@ -64,9 +68,8 @@ func presetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	presetName := parts[1]
 	textToPrint := parts[2]
 
-	// Get the configuration and printer
+	// Get the configuration
 	cfg := config.Get()
-	printer := utils.GetPrinterFromContext(ctx)
 
 	// Look up the preset
 	preset := cfg.Printer.GetPreset(presetName)
@@ -75,12 +78,37 @@ func presetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 		return
 	}
 
-	logger.Info("Using preset '%s' (font size: %d, font family: %s) to print: %s from %s",
+	// Presets can be routed to their own printer
+	printer, err := presetPrinter(ctx, update.Message.Chat.ID, preset)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("❌ Cannot use preset '%s': %s", presetName, err.Error()),
+		})
+		return
+	}
+
+	logger.Info("Using preset '%s' (font size: %s, font family: %s) to print: %s from %s",
 		presetName, preset.FontSize, preset.FontFamily, textToPrint, update.Message.From.Username)
 
-	// Print the label with the preset's font size and family
-	err := printer.PrintLabelWithPreset(textToPrint, preset)
+	// Print the label with the preset's template, font size and family
+	counter := utils.GetHistoryFromContext(ctx).ReserveCounter(presetName, false)
+	text, err := renderPresetText(update.Message, presetName, preset, textToPrint, counter)
+	var label printers.Label
+	if err == nil {
+		label, err = printer.PresetLabel(text, preset)
+	}
+	var job *printers.Job
+	if err == nil {
+		meta := jobMetaFromMessage(update.Message, presetName)
+		meta.Counter = counter.Value()
+		job, err = runPrinterJob(ctx, b, update.Message.Chat.ID, printer, printers.JobPrint, label, meta)
+	}
 	if err != nil {
+		// Submitted jobs give their counter value back once they fail
+		if job == nil {
+			counter.Release()
+		}
 		logger.Error("Error printing label with preset '%s': %v", presetName, err)
 
 		// Inform the user about the error
@@ -92,16 +120,55 @@ func presetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	}
 
 	// Send success message
-	fontInfo := fmt.Sprintf("📏 Font size: %d", preset.FontSize)
+	fontInfo := fmt.Sprintf("📏 Font size: %s", preset.FontSize)
 	if preset.FontFamily != "" {
 		fontInfo += fmt.Sprintf(", Font: %s", preset.FontFamily)
 	}
 	b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   fmt.Sprintf("✅ Label printed successfully using preset '%s'!\n%s", presetName, fontInfo),
+		Text:   fmt.Sprintf("✅ %d label(s) printed successfully using preset '%s'!\n%s", label.Total(), presetName, fontInfo),
 	})
 }
 
+// Returns the printer for a preset, the chat's printer unless the preset names its own
+func presetPrinter(ctx context.Context, chatID int64, preset *config.Preset) (*printers.Printer, error) {
+	if preset.Printer == "" {
+		return utils.GetPrinterFromContext(ctx), nil
+	}
+
+	pool := utils.GetPrintersFromContext(ctx)
+	if pool == nil {
+		return nil, fmt.Errorf("no printers are configured")
+	}
+	printer := pool.ForPreset(chatID, preset)
+	if printer == nil {
+		return nil, fmt.Errorf("printer '%s' is not available", preset.Printer)
+	}
+	return printer, nil
+}
+
+// Renders the preset's template for the text, or returns the text if the preset has none
+// The template takes its counter value from the reservation
+func renderPresetText(message *models.Message, presetName string, preset *config.Preset, text string, counter *history.Reservation) (string, error) {
+	if preset.Template == "" {
+		return text, nil
+	}
+
+	user := message.From.Username
+	if user == "" {
+		user = message.From.FirstName
+	}
+
+	data := templates.Data{
+		Text:        strings.TrimSpace(text),
+		User:        user,
+		Preset:      presetName,
+		NextCounter: counter.Next,
+	}
+
+	return templates.Render(presetName, preset.Template, config.Get().Printer.DateFormat, data)
+}
+
 func sendPresetUsage(ctx context.Context, b *bot.Bot, chatID int64) {
 	cfg := config.Get()
 	presetNames := cfg.Printer.GetPresetNames()
@@ -120,11 +187,31 @@ func sendPresetUsage(ctx context.Context, b *bot.Bot, chatID int64) {
 	for _, name := range presetNames {
 		preset := cfg.Printer.GetPreset(name)
 		if preset != nil {
-			fontInfo := fmt.Sprintf("font size: %d", preset.FontSize)
+			fontInfo := fmt.Sprintf("font size: %s", preset.FontSize)
 			if preset.FontFamily != "" {
 				fontInfo += fmt.Sprintf(", font: %s", preset.FontFamily)
 			}
+			if preset.Barcode != "" {
+				fontInfo += fmt.Sprintf(", barcode: %s", preset.Barcode)
+			}
+			if preset.Series != nil {
+				fontInfo += fmt.Sprintf(", series of %d", preset.Series.Count)
+			}
+			if preset.Copies > 1 {
+				fontInfo += fmt.Sprintf(", %d copies", preset.Copies)
+			}
+			if preset.Printer != "" {
+				fontInfo += fmt.Sprintf(", printer: %s", preset.Printer)
+			}
+			if preset.Layout != nil {
+				if layout := printers.LayoutFromConfig(preset.Layout).DisplayText(); layout != "" {
+					fontInfo += ", " + layout
+				}
+			}
 			message.WriteString(fmt.Sprintf("• %s - %s (%s)\n", name, preset.Description, fontInfo))
+			if preset.Template != "" {
+				message.WriteString(fmt.Sprintf("   template: %s\n", preset.Template))
+			}
 		}
 	}
 
@@ -2,6 +2,7 @@ package telegram
 
 import (
 	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
 	"brother-cube-telegram/utils"
 	"bytes"
 	"context"
@@ -41,7 +42,11 @@ func previewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 		return
 	}
 
-	img, err := printer.PreviewLabel(rawText, update.Message.From.ID)
+	label, err := printer.DefaultLabel(rawText)
+	var job *printers.Job
+	if err == nil {
+		job, err = runPrinterJob(ctx, b, update.Message.Chat.ID, printer, printers.JobPreview, label, jobMetaFromMessage(update.Message, ""))
+	}
 
 	if err != nil {
 		logger.Error("Error generating label preview: %v", err)
@@ -54,7 +59,7 @@ func previewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 
 	b.SendPhoto(ctx, &bot.SendPhotoParams{
 		ChatID:  update.Message.Chat.ID,
-		Photo:   &models.InputFileUpload{Filename: "image.png", Data: bytes.NewReader(img)},
+		Photo:   &models.InputFileUpload{Filename: "image.png", Data: bytes.NewReader(job.Preview())},
 		Caption: "Preview of your label",
 	})
 }
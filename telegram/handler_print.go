@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/utils"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func printHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Defer a recovery function to catch any panics
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic in printHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
+
+			// Try to send an error message to the user if possible
+			if update.Message != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "❌ An error occurred while processing your print command. Please try again.",
+				})
+			}
+		}
+	}()
+
+	// Check if update has a message and message has text
+	if update.Message == nil {
+		logger.Warn("Received update without message in printHandler")
+		return
+	}
+
+	if update.Message.From == nil {
+		logger.Warn("Received message without sender info in printHandler")
+		return
+	}
+
+	// Parse the command: /print [--flags] <text>, keeping the newlines of the text
+	_, args, _ := strings.Cut(update.Message.Text, " ")
+	flags, text, err := parsePrintFlags(args)
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessageWithError("print", fmt.Sprintf("Invalid option: %s.", err.Error())),
+		})
+		return
+	}
+
+	if strings.TrimSpace(text) == "" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessage("print"),
+		})
+		return
+	}
+
+	printer := utils.GetPrinterFromContext(ctx)
+
+	label, err := printer.DefaultLabel(text)
+	if err == nil {
+		flags.apply(&label)
+		err = label.Validate(printer.Config().MaxLines)
+	}
+	if err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessageWithError("print", fmt.Sprintf("Invalid label: %s.", err.Error())),
+		})
+		return
+	}
+
+	kind := printers.JobPrint
+	if flags.preview {
+		kind = printers.JobPreview
+	}
+
+	logger.Info("Print command (%s, %d label(s)): %s from %s", kind, label.Total(), label.DisplayText(), update.Message.From.Username)
+
+	job, err := runPrinterJob(ctx, b, update.Message.Chat.ID, printer, kind, label, jobMetaFromMessage(update.Message, ""))
+	if err != nil {
+		logger.Error("Error running print command: %v", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Failed to print label: " + err.Error(),
+		})
+		return
+	}
+
+	if kind == printers.JobPreview {
+		b.SendPhoto(ctx, &bot.SendPhotoParams{
+			ChatID:  update.Message.Chat.ID,
+			Photo:   &models.InputFileUpload{Filename: "image.png", Data: bytes.NewReader(job.Preview())},
+			Caption: fmt.Sprintf("Preview of your %d label(s)", label.Total()),
+		})
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   fmt.Sprintf("✅ %d label(s) printed successfully!", label.Total()),
+	})
+}
@@ -0,0 +1,117 @@
+package telegram
+
+import (
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/utils"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func printerHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Defer a recovery function to catch any panics
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic in printerHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
+
+			// Try to send an error message to the user if possible
+			if update.Message != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "❌ An error occurred while processing your printer command. Please try again.",
+				})
+			}
+		}
+	}()
+
+	// Check if update has a message and message has text
+	if update.Message == nil {
+		logger.Warn("Received update without message in printerHandler")
+		return
+	}
+
+	if update.Message.From == nil {
+		logger.Warn("Received message without sender info in printerHandler")
+		return
+	}
+
+	pool := utils.GetPrintersFromContext(ctx)
+	if pool == nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ No printers are configured.",
+		})
+		return
+	}
+
+	// Parse the command: /printer [name]
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		sendPrinterList(ctx, b, update.Message.Chat.ID)
+		return
+	}
+
+	name := parts[1]
+	if err := pool.Select(update.Message.Chat.ID, name); err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessageWithError("printer", fmt.Sprintf("Unknown printer '%s'. Use /printer to list the printers.", name)),
+		})
+		return
+	}
+
+	logger.Info("Chat %d switched to printer '%s' by %s", update.Message.Chat.ID, name, update.Message.From.Username)
+
+	message := fmt.Sprintf("✅ This chat now prints on '%s'.", name)
+	if pool.Get(name) == nil {
+		message += "\n⚠️ The printer is currently not available."
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   message,
+	})
+}
+
+// Lists all printers, marking the one used by the chat
+func sendPrinterList(ctx context.Context, b *bot.Bot, chatID int64) {
+	pool := utils.GetPrintersFromContext(ctx)
+	current := pool.NameForChat(chatID)
+
+	var message strings.Builder
+	message.WriteString("🖨 Printers:\n\n")
+	for _, name := range pool.Names() {
+		marker := "•"
+		if name == current {
+			marker = "➡️"
+		}
+
+		state := "available"
+		printer := pool.Get(name)
+		if printer == nil {
+			state = "not available"
+		} else if info := printer.LastInfo(); info != nil {
+			state = fmt.Sprintf("%s, %dmm tape", info.Model, info.TapeWidthMM)
+		}
+		message.WriteString(fmt.Sprintf("%s %s (%s)\n", marker, name, state))
+	}
+	message.WriteString("\n💡 Usage: /printer <name>")
+
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   message.String(),
+	})
+	if err != nil {
+		logger.Error("Failed to send printer list: %v", err)
+	}
+}
+
+// Returns true if the text is a /printer command
+func isPrinterCommand(text string) bool {
+	command, _, _ := strings.Cut(text, " ")
+	command, _, _ = strings.Cut(command, "@")
+	return command == "/printer"
+}
@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/utils"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func qrHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	handleQRCommand(ctx, b, update, "qr", printers.JobPrint)
+}
+
+func qrPreviewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	handleQRCommand(ctx, b, update, "qrpreview", printers.JobPreview)
+}
+
+// Shared implementation of /qr and /qrpreview
+func handleQRCommand(ctx context.Context, b *bot.Bot, update *models.Update, command string, kind printers.JobKind) {
+	// Defer a recovery function to catch any panics
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic in qrHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
+
+			// Try to send an error message to the user if possible
+			if update.Message != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "❌ An error occurred while processing your QR code command. Please try again.",
+				})
+			}
+		}
+	}()
+
+	// Check if update has a message and message has text
+	if update.Message == nil {
+		logger.Warn("Received update without message in qrHandler")
+		return
+	}
+
+	if update.Message.From == nil {
+		logger.Warn("Received message without sender info in qrHandler")
+		return
+	}
+
+	// Parse the command: /qr <data> [caption]
+	parts := strings.SplitN(strings.TrimSpace(update.Message.Text), " ", 3)
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessage(command),
+		})
+		return
+	}
+
+	data := strings.TrimSpace(parts[1])
+	caption := ""
+	if len(parts) > 2 {
+		caption = strings.TrimSpace(parts[2])
+	}
+
+	printer := utils.GetPrinterFromContext(ctx)
+	cfg := printer.Config()
+	label := printers.Label{Text: caption, FontSize: int(cfg.FontSize), QRCode: data, Layout: printers.LayoutFromConfig(&cfg.Layout)}
+	if err := label.Validate(cfg.MaxLines); err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessageWithError(command, fmt.Sprintf("Invalid QR code label: %s.", err.Error())),
+		})
+		return
+	}
+
+	logger.Info("QR code %s requested: %s from %s", kind, label.DisplayText(), update.Message.From.Username)
+
+	job, err := runPrinterJob(ctx, b, update.Message.Chat.ID, printer, kind, label, jobMetaFromMessage(update.Message, ""))
+	if err != nil {
+		logger.Error("Error processing QR code label: %v", err)
+
+		// Inform the user about the error
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("❌ Failed to %s QR code label: %s", kind, err.Error()),
+		})
+		return
+	}
+
+	if kind == printers.JobPreview {
+		b.SendPhoto(ctx, &bot.SendPhotoParams{
+			ChatID:  update.Message.Chat.ID,
+			Photo:   &models.InputFileUpload{Filename: "qr-preview.png", Data: bytes.NewReader(job.Preview())},
+			Caption: fmt.Sprintf("Preview of your QR code label\n🔗 %s", data),
+		})
+		return
+	}
+
+	// Send success message
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   "✅ QR code label printed successfully!",
+	})
+}
@@ -0,0 +1,126 @@
+package telegram
+
+import (
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"brother-cube-telegram/utils"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func reprintHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Defer a recovery function to catch any panics
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Recovered from panic in reprintHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
+
+			// Try to send an error message to the user if possible
+			if update.Message != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "❌ An error occurred while processing your reprint command. Please try again.",
+				})
+			}
+		}
+	}()
+
+	// Check if update has a message and message has text
+	if update.Message == nil {
+		logger.Warn("Received update without message in reprintHandler")
+		return
+	}
+
+	if update.Message.From == nil {
+		logger.Warn("Received message without sender info in reprintHandler")
+		return
+	}
+
+	// Parse the command: /reprint <number>
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessage("reprint"),
+		})
+		return
+	}
+
+	number, err := strconv.Atoi(parts[1])
+	if err != nil || number < 1 || number > maxHistoryEntries {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessageWithError("reprint", fmt.Sprintf("Invalid number '%s'. Use a number shown by /history.", parts[1])),
+		})
+		return
+	}
+
+	store := utils.GetHistoryFromContext(ctx)
+	if store == nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Print history is not available.",
+		})
+		return
+	}
+
+	entries, err := store.Recent(update.Message.From.ID, number)
+	if err != nil {
+		logger.Error("Error reading print history: %v", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Failed to read print history: " + err.Error(),
+		})
+		return
+	}
+
+	if len(entries) < number {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("❌ Label %d not found in your history. Use /history to see your labels.", number),
+		})
+		return
+	}
+
+	entry := entries[number-1]
+	if !entry.Reprintable() {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("❌ Label %d cannot be reprinted: its image was too large to keep in the history. Send the image again to print it.", number),
+		})
+		return
+	}
+
+	// Reprint on the same printer if it is still available
+	printer := utils.GetPrinterFromContext(ctx)
+	if pool := utils.GetPrintersFromContext(ctx); pool != nil && entry.Printer != "" {
+		if original := pool.Get(entry.Printer); original != nil {
+			printer = original
+		}
+	}
+
+	logger.Info("Reprinting history entry %d: %s from %s", entry.ID, entry.Label.DisplayText(), update.Message.From.Username)
+
+	_, err = runPrinterJob(ctx, b, update.Message.Chat.ID, printer, printers.JobPrint, entry.Label, jobMetaFromMessage(update.Message, entry.Preset))
+	if err != nil {
+		logger.Error("Error reprinting label: %v", err)
+
+		// Inform the user about the error
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "❌ Failed to reprint label: " + err.Error(),
+		})
+		return
+	}
+
+	// Send success message
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   fmt.Sprintf("✅ Label \"%s\" reprinted successfully!", entry.Label.DisplayText()),
+	})
+}
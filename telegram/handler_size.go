@@ -1,11 +1,12 @@
 package telegram
 
 import (
+	"brother-cube-telegram/config"
 	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
 	"brother-cube-telegram/utils"
 	"context"
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/go-telegram/bot"
@@ -17,6 +18,7 @@ func sizeHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Error("Recovered from panic in sizeHandler: %v", r)
+			countHandlerError(ctx, handlerErrorPanic)
 
 			// Try to send an error message to the user if possible
 			if update.Message != nil {
@@ -70,13 +72,13 @@ func sizeHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	fontSize := parts[1]
 	label := strings.TrimSpace(strings.Join(parts[2:], " "))
 
-	// Try to convert fontSize to int
-	fontSizeInt, err := strconv.Atoi(fontSize)
+	// Try to convert fontSize to a number or "auto"
+	parsedFontSize, err := config.ParseFontSize(fontSize)
 	if err != nil {
 		logger.Error("Invalid font size: %v", err)
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
-			Text:   GetCommandUsageMessageWithError("size", fmt.Sprintf("Invalid font size '%s'. Please provide a valid number.", fontSize)),
+			Text:   GetCommandUsageMessageWithError("size", fmt.Sprintf("Invalid font size '%s'. Please provide a valid number or 'auto'.", fontSize)),
 		})
 		return
 	}
@@ -90,8 +92,17 @@ func sizeHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 		return
 	}
 
+	sizedLabel := printers.Label{Text: label, FontSize: int(parsedFontSize), Layout: printers.LayoutFromConfig(&printer.Config().Layout)}
+	if err := sizedLabel.Validate(printer.Config().MaxLines); err != nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   GetCommandUsageMessageWithError("size", fmt.Sprintf("Invalid label: %s.", err.Error())),
+		})
+		return
+	}
+
 	// Wrap the printer call in error handling
-	err = printer.PrintLabel(label, fontSizeInt)
+	_, err = runPrinterJob(ctx, b, update.Message.Chat.ID, printer, printers.JobPrint, sizedLabel, jobMetaFromMessage(update.Message, ""))
 	if err != nil {
 		logger.Error("Error printing label: %v", err)
 
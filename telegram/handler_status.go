@@ -1,8 +1,12 @@
 package telegram
 
 import (
+	"brother-cube-telegram/printers"
 	"brother-cube-telegram/utils"
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -11,18 +15,59 @@ import (
 func statusHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	printer := utils.GetPrinterFromContext(ctx)
 
-	// Use the printer to get the status
-	status, err := printer.GetPrinterInfo()
-	if err != nil {
-		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
-			Text:   "Error getting printer status: " + err.Error(),
-		})
-		return
+	// A switched off printer is not woken up, its last known info is shown instead
+	var message string
+	if printer.Power().IsOn() {
+		info, err := printer.GetPrinterInfo()
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Error getting printer status: " + err.Error() + "\n" + formatPowerStats(printer.Power().Stats()),
+			})
+			return
+		}
+		message = formatPrinterInfo(info)
+	} else if info := printer.LastInfo(); info != nil {
+		message = formatPrinterInfo(info) + "\n(last known status)"
+	}
+
+	message = strings.TrimSpace(message + "\n\n" + formatPowerStats(printer.Power().Stats()))
+	if stats := printer.Power().Stats(); stats.Managed && stats.PowerCycles > 0 {
+		message += fmt.Sprintf("\n⏱ On for %s in total, switched on %d time(s)", stats.OnTime.Round(time.Second), stats.PowerCycles)
+	}
+	if pool := utils.GetPrintersFromContext(ctx); pool != nil && len(pool.Names()) > 1 {
+		message = fmt.Sprintf("🏷 Printer '%s' (switch with /printer)\n", printer.Name()) + message
 	}
 
 	b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   "Printer status: " + status,
+		Text:   message,
 	})
 }
+
+// Formats printer info as a readable status message
+func formatPrinterInfo(info *printers.PrinterInfo) string {
+	var message strings.Builder
+
+	model := info.Model
+	if model == "" {
+		model = "Unknown model"
+	}
+	message.WriteString(fmt.Sprintf("🖨 Printer status: %s\n\n", model))
+
+	message.WriteString(fmt.Sprintf("📏 Tape: %dmm %s\n", info.TapeWidthMM, utils.ValueOrUnknown(info.MediaType)))
+	message.WriteString(fmt.Sprintf("🎨 Colors: %s text on %s tape\n", utils.ValueOrUnknown(info.TextColor), utils.ValueOrUnknown(info.TapeColor)))
+	message.WriteString(fmt.Sprintf("🔢 Printable height: %dpx", info.TapeMaxPixels))
+	if info.PrinterMaxPixels > 0 {
+		message.WriteString(fmt.Sprintf(" (print head: %dpx)", info.PrinterMaxPixels))
+	}
+	message.WriteString("\n\n")
+
+	if info.HasErrors() {
+		message.WriteString(fmt.Sprintf("⚠️ Errors: %s", strings.Join(info.Errors.Messages(), ", ")))
+	} else {
+		message.WriteString("✅ No errors reported")
+	}
+
+	return message.String()
+}
@@ -0,0 +1,55 @@
+package telegram
+
+import (
+	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// Submits a job to the printer queue and waits for its result
+// Lets the user know when other jobs have to finish first
+func runPrinterJob(ctx context.Context, b *bot.Bot, chatID int64, printer *printers.Printer, kind printers.JobKind, label printers.Label, meta printers.JobMeta) (*printers.Job, error) {
+	job, err := printer.Submit(kind, label, meta)
+	if err != nil {
+		countHandlerError(ctx, handlerErrorJob)
+		return nil, err
+	}
+
+	if ahead := printer.JobsAhead(job); ahead > 0 {
+		logger.Debug("Job %d waiting behind %d other job(s)", job.ID, ahead)
+		_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("⏳ Printer is busy, your job #%d is queued behind %d other job(s)...", job.ID, ahead),
+		})
+		if err != nil {
+			logger.Error("Failed to send queue notice: %v", err)
+		}
+	}
+
+	err = job.Wait(ctx)
+	if err != nil {
+		countHandlerError(ctx, handlerErrorJob)
+	}
+
+	// Show a timeout on its own instead of buried in the error chain
+	var timeout *printers.TimeoutError
+	if errors.As(err, &timeout) {
+		return job, timeout
+	}
+	return job, err
+}
+
+// Builds the job metadata for a message, preset may be empty
+func jobMetaFromMessage(message *models.Message, preset string) printers.JobMeta {
+	return printers.JobMeta{
+		UserID:   message.From.ID,
+		ChatID:   message.Chat.ID,
+		Username: message.From.Username,
+		Preset:   preset,
+	}
+}
@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Kinds of handler errors
+const (
+	// A handler panicked
+	handlerErrorPanic = "panic"
+	// A print or preview job started by a handler failed
+	handlerErrorJob = "job"
+)
+
+var (
+	handledUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "brother_cube",
+		Name:      "telegram_updates_total",
+		Help:      "Telegram messages handled, by handler.",
+	}, []string{"handler"})
+
+	handlerErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "brother_cube",
+		Name:      "telegram_handler_errors_total",
+		Help:      "Errors of Telegram handlers, by handler and kind.",
+	}, []string{"handler", "kind"})
+)
+
+// Counts the update and adds the name of its handler to the context for countHandlerError
+func metricsMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		handler := handlerName(update)
+		handledUpdates.WithLabelValues(handler).Inc()
+
+		ctx = context.WithValue(ctx, "handler", handler)
+		next(ctx, b, update)
+	}
+}
+
+// Counts an error of the handler running for the context
+func countHandlerError(ctx context.Context, kind string) {
+	handler, ok := ctx.Value("handler").(string)
+	if !ok {
+		handler = "unknown"
+	}
+	handlerErrors.WithLabelValues(handler, kind).Inc()
+}
+
+// Returns the name of the handler for an update: the command, "default" for other messages
+// Unregistered commands share "unknown" so users cannot create new label values
+func handlerName(update *models.Update) string {
+	if update.Message == nil {
+		return "unknown"
+	}
+	if !strings.HasPrefix(update.Message.Text, "/") {
+		return "default"
+	}
+
+	command, _, _ := strings.Cut(strings.Fields(update.Message.Text)[0], "@")
+	command = strings.TrimPrefix(command, "/")
+	if _, exists := commandRegistry[command]; !exists {
+		return "unknown"
+	}
+	return command
+}
@@ -31,7 +31,7 @@ func authorizationMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 		}
 
 		// Parse the comma-separated list of allowed chat IDs
-		allowedChatIDs := parseAllowedChatIDs(allowedChatIDsStr)
+		allowedChatIDs := parseChatIDs(allowedChatIDsStr, "TELEGRAM_ALLOWED_CHAT_IDS")
 
 		// Check if the current chat ID is in the allowed list
 		if !isAuthorized(chatID, allowedChatIDs) {
@@ -46,7 +46,8 @@ func authorizationMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 }
 
 // Parses a comma-separated string of chat IDs into a slice of int64
-func parseAllowedChatIDs(chatIDsStr string) []int64 {
+// variable names the environment variable the IDs come from, for error messages
+func parseChatIDs(chatIDsStr string, variable string) []int64 {
 	var allowedChatIDs []int64
 
 	// Split by comma and parse each ID
@@ -59,7 +60,7 @@ func parseAllowedChatIDs(chatIDsStr string) []int64 {
 
 		chatID, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
-			logger.Error("Invalid chat ID in TELEGRAM_ALLOWED_CHAT_IDS: %s", idStr)
+			logger.Error("Invalid chat ID in %s: %s", variable, idStr)
 			continue
 		}
 
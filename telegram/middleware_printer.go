@@ -2,18 +2,41 @@ package telegram
 
 import (
 	"brother-cube-telegram/logger"
+	"brother-cube-telegram/printers"
 	"brother-cube-telegram/utils"
 	"context"
+	"fmt"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
 
 // printerMiddlewareHandler handles the actual printer checking logic
+// The printer routed to the chat is added to the handler context
 func printerMiddlewareHandler(mainCtx context.Context, next bot.HandlerFunc) bot.HandlerFunc {
 	return func(handlerCtx context.Context, b *bot.Bot, update *models.Update) {
-		// Get printer from the main context
-		printer := utils.GetPrinterFromContext(mainCtx)
+		// Get the printers from the main context
+		pool := utils.GetPrintersFromContext(mainCtx)
+		if pool != nil {
+			handlerCtx = context.WithValue(handlerCtx, "printers", pool)
+		}
+
+		// The /printer command has to work while the chat's printer is unavailable to switch away from it
+		if update.Message != nil && isPrinterCommand(update.Message.Text) {
+			next(handlerCtx, b, update)
+			return
+		}
+
+		var printer *printers.Printer
+		name := ""
+		if pool != nil {
+			var chatID int64
+			if update.Message != nil {
+				chatID = update.Message.Chat.ID
+			}
+			name = pool.NameForChat(chatID)
+			printer = pool.Get(name)
+		}
 
 		if printer != nil {
 			// Printer is available - add it to handler context and proceed
@@ -26,7 +49,7 @@ func printerMiddlewareHandler(mainCtx context.Context, next bot.HandlerFunc) bot
 		if update.Message != nil {
 			_, err := b.SendMessage(handlerCtx, &bot.SendMessageParams{
 				ChatID: update.Message.Chat.ID,
-				Text:   "❌ Printer is not available. Please check the printer connection.",
+				Text:   fmt.Sprintf("❌ Printer '%s' is not available. Please check the printer connection or switch with /printer.", name),
 			})
 			if err != nil {
 				logger.Error("Error sending printer unavailable message: %v", err)
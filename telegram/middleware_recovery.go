@@ -16,6 +16,7 @@ func recoveryMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 			if r := recover(); r != nil {
 				// Log the panic with stack trace
 				logger.Error("PANIC recovered: %v\nStack trace:\n%s", r, debug.Stack())
+				handlerErrors.WithLabelValues(handlerName(update), handlerErrorPanic).Inc()
 
 				// Try to send a generic error message to the user
 				if update.Message != nil {
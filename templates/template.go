@@ -0,0 +1,95 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Date format used when none is configured, e.g. 17.10.2026
+const DefaultDateFormat = "02.01.2006"
+
+// Values available to a label template
+type Data struct {
+	// Text sent by the user
+	Text string
+	// Name of the user printing the label
+	User string
+	// Name of the preset the template belongs to
+	Preset string
+	// Returns the counter value of the label, only called if the template uses {{.Counter}}
+	NextCounter func() (uint64, error)
+}
+
+// Returns the counter value of the label
+func (d Data) Counter() (uint64, error) {
+	if d.NextCounter == nil {
+		return 0, fmt.Errorf("counter is not available")
+	}
+	return d.NextCounter()
+}
+
+// Returns the functions available in label templates, dates are formatted with dateFormat
+func funcs(dateFormat string) template.FuncMap {
+	return template.FuncMap{
+		// Today's date, e.g. {{today}}
+		"today": func() string {
+			return time.Now().Format(dateFormat)
+		},
+		// The date a number of days from today, e.g. {{addDays 7}}
+		"addDays": func(days int) string {
+			return time.Now().AddDate(0, 0, days).Format(dateFormat)
+		},
+		// Today's date or time in a custom Go layout, e.g. {{date "2006-01"}}
+		"date": func(layout string) string {
+			return time.Now().Format(layout)
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}
+}
+
+// Parses a label template, an empty dateFormat falls back to DefaultDateFormat
+func Parse(name string, body string, dateFormat string) (*template.Template, error) {
+	if dateFormat == "" {
+		dateFormat = DefaultDateFormat
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(funcs(dateFormat)).Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// Parses the template and executes it with example data to catch unknown fields and bad arguments
+func Validate(name string, body string, dateFormat string) error {
+	tmpl, err := Parse(name, body, dateFormat)
+	if err != nil {
+		return err
+	}
+
+	example := Data{
+		Text:        "Example",
+		User:        "user",
+		Preset:      name,
+		NextCounter: func() (uint64, error) { return 1, nil },
+	}
+	return tmpl.Execute(&bytes.Buffer{}, example)
+}
+
+// Renders a label template with the given data
+func Render(name string, body string, dateFormat string, data Data) (string, error) {
+	tmpl, err := Parse(name, body, dateFormat)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %v", err)
+	}
+
+	var output bytes.Buffer
+	if err := tmpl.Execute(&output, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %v", err)
+	}
+	return output.String(), nil
+}
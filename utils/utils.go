@@ -2,13 +2,16 @@ package utils
 
 import (
 	"brother-cube-telegram/gpio"
+	"brother-cube-telegram/history"
 	"brother-cube-telegram/logger"
 	"brother-cube-telegram/printers"
 	"context"
 )
 
 const printerCtxKey string = "printer"
+const printersCtxKey string = "printers"
 const relayCtxKey string = "relay"
+const historyCtxKey string = "history"
 
 // GetPrinterFromContext gets printer from context
 func GetPrinterFromContext(ctx context.Context) *printers.Printer {
@@ -19,11 +22,37 @@ func GetPrinterFromContext(ctx context.Context) *printers.Printer {
 	return nil
 }
 
+// GetPrintersFromContext gets the pool of all printers from context
+func GetPrintersFromContext(ctx context.Context) *printers.Pool {
+	if pool, ok := ctx.Value(printersCtxKey).(*printers.Pool); ok {
+		return pool
+	}
+	logger.Warn("Printers not found in context")
+	return nil
+}
+
 // GetRelayFromContext gets relay from context
-func GetRelayFromContext(ctx context.Context) *gpio.Relay {
-	if relay, ok := ctx.Value(relayCtxKey).(*gpio.Relay); ok {
+func GetRelayFromContext(ctx context.Context) gpio.Switch {
+	if relay, ok := ctx.Value(relayCtxKey).(gpio.Switch); ok {
 		return relay
 	}
 	logger.Warn("Relay not found in context")
 	return nil
 }
+
+// GetHistoryFromContext gets the print history store from context
+func GetHistoryFromContext(ctx context.Context) *history.Store {
+	if store, ok := ctx.Value(historyCtxKey).(*history.Store); ok {
+		return store
+	}
+	logger.Warn("History store not found in context")
+	return nil
+}
+
+// ValueOrUnknown returns the value, or "unknown" if it is empty
+func ValueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}